@@ -30,8 +30,11 @@ func Canonical(dst, data []byte, eo EncodeOptions, opts ...DecodeOption) ([]byte
 	for _, o := range opts {
 		o(&c.decodeOptions)
 	}
+	if err := c.decodeOptions.CheckBytes(data); err != nil {
+		return nil, decodeErrorAt(err, 0, "")
+	}
 	if _, err := c.canonicalize(data); err != nil {
-		return nil, err
+		return nil, decodeErrorAt(err, 0, "")
 	}
 	return c.ret, nil
 }
@@ -70,6 +73,12 @@ func (c *canonicalizer) appendInt(raw []byte, n int) error {
 }
 
 func (c *canonicalizer) canonicalize_array(data []byte, offset, elements int) (int, error) {
+	if err := c.decodeOptions.Descend(); err != nil {
+		return 0, err
+	}
+	if err := c.decodeOptions.CheckElements(elements, 1, len(data)-offset); err != nil {
+		return 0, err
+	}
 	processed := make([][]byte, 0, elements)
 	var neededLen int
 	for i := 0; elements > i; i++ {
@@ -81,7 +90,7 @@ func (c *canonicalizer) canonicalize_array(data []byte, offset, elements int) (i
 		defer canonicalizerPool.Put(sc.ret)
 		consume, err := sc.canonicalize(data[offset:])
 		if err != nil {
-			return 0, err
+			return 0, decodeErrorAt(err, offset, internal.IndexSegment(i))
 		}
 		offset += consume
 		if bytes.Equal(sc.ret, canonicalVoidExtension) {
@@ -103,6 +112,12 @@ func (c *canonicalizer) canonicalize_array(data []byte, offset, elements int) (i
 }
 
 func (c *canonicalizer) canonicalize_map(data []byte, offset, elements int) (int, error) {
+	if err := c.decodeOptions.Descend(); err != nil {
+		return 0, err
+	}
+	if err := c.decodeOptions.CheckElements(elements, 2, len(data)-offset); err != nil {
+		return 0, err
+	}
 	reindex := make([]int, 0, elements)
 	keys := make([][]byte, 0, elements)
 	values := make([][]byte, 0, elements)
@@ -116,7 +131,7 @@ func (c *canonicalizer) canonicalize_map(data []byte, offset, elements int) (int
 		defer canonicalizerPool.Put(sc.ret)
 		consume, err := sc.canonicalize(data[offset:])
 		if err != nil {
-			return 0, err
+			return 0, decodeErrorAt(err, offset, "")
 		}
 		offset += consume
 		key := sc.ret
@@ -136,7 +151,7 @@ func (c *canonicalizer) canonicalize_map(data []byte, offset, elements int) (int
 		defer canonicalizerPool.Put(sc.ret)
 		consume, err = sc.canonicalize(data[offset:])
 		if err != nil {
-			return 0, err
+			return 0, decodeErrorAt(err, offset, keySegment(key, c.decodeOptions))
 		}
 		offset += consume
 		if bytes.Equal(sc.ret, canonicalVoidExtension) {
@@ -166,6 +181,12 @@ func (c *canonicalizer) canonicalize_map(data []byte, offset, elements int) (int
 var canonicalVoidExtension = []byte{0xc7, 0, 19}
 
 func (c *canonicalizer) canonicalize_ext(data []byte, extType int8) error {
+	switch extType {
+	case 17, 18, 20:
+		if err := c.decodeOptions.Descend(); err != nil {
+			return err
+		}
+	}
 	switch extType {
 	case -1:
 		// Re-encode to see if we can encode it smaller without losing information.
@@ -193,7 +214,7 @@ func (c *canonicalizer) canonicalize_ext(data []byte, extType int8) error {
 	case 18: // Flavor pick
 		if j, err := internal.DecodeFlavorPick(data, c.decodeOptions); err == nil { // == nil
 			_, err = c.canonicalize(data[j:])
-			return err
+			return shiftDecodeError(err, j)
 		}
 		return c.canonicalize_flavor(data)
 
@@ -204,7 +225,7 @@ func (c *canonicalizer) canonicalize_ext(data []byte, extType int8) error {
 	case 20: // Injection
 		if b, err := internal.DecodeInjectionExtension(data, c.decodeOptions); err == nil { // == nil
 			_, err = c.canonicalize(b)
-			return err
+			return injectedDecodeError(err)
 		}
 
 	default:
@@ -266,7 +287,7 @@ func (c *canonicalizer) canonicalize_flavor(data []byte) error {
 		}
 		defer canonicalizerPool.Put(sc.ret)
 		if _, err := sc.canonicalize(full[j:]); err != nil {
-			return err
+			return shiftDecodeError(err, int(j))
 		}
 		canon[i] = sc.ret
 	}
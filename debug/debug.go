@@ -27,6 +27,9 @@ func Fdump(dst io.Writer, data []byte, opts ...fastmsgpack.DecodeOption) error {
 	for _, o := range opts {
 		o(&p.options)
 	}
+	if err := p.options.CheckBytes(data); err != nil {
+		return err
+	}
 	if _, err := p.debugValue(data); err != nil {
 		_ = p.w.Flush()
 		return err
@@ -35,6 +38,10 @@ func Fdump(dst io.Writer, data []byte, opts ...fastmsgpack.DecodeOption) error {
 }
 
 func (p *printer) debugValue_array(data []byte, offset, elements int) (int, error) {
+	if err := p.descend(elements, 1, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer p.options.Ascend()
 	if err := p.printf("[%02x] array (%d elements)", data[:offset], elements); err != nil {
 		return 0, err
 	}
@@ -54,6 +61,10 @@ func (p *printer) debugValue_array(data []byte, offset, elements int) (int, erro
 }
 
 func (p *printer) debugValue_map(data []byte, offset, elements int) (int, error) {
+	if err := p.descend(elements, 2, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer p.options.Ascend()
 	if err := p.printf("[%02x] map (%d elements)", data[:offset], elements); err != nil {
 		return 0, err
 	}
@@ -79,6 +90,14 @@ func (p *printer) debugValue_map(data []byte, offset, elements int) (int, error)
 	return offset, nil
 }
 
+// descend checks the limits when entering a map, array or wrapping extension. options.Ascend must be called when leaving it.
+func (p *printer) descend(elements, perElement, remaining int) error {
+	if err := p.options.Descend(); err != nil {
+		return err
+	}
+	return p.options.CheckElements(elements, perElement, remaining)
+}
+
 func (p *printer) printf(f string, args ...any) error {
 	if _, err := p.w.WriteString(strings.Repeat("\t", p.indent)); err != nil {
 		return err
@@ -118,6 +137,13 @@ func (p *printer) appendFloat64(header []byte, f float64) error {
 }
 
 func (p *printer) debugValue_ext(header, data []byte, extType int8) error {
+	switch extType {
+	case 17, 18:
+		if err := p.descend(0, 0, 0); err != nil {
+			return err
+		}
+		defer p.options.Ascend()
+	}
 	switch extType {
 	case -1:
 		ts, err := internal.DecodeTimestamp(data)
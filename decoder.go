@@ -2,6 +2,7 @@ package fastmsgpack
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/hexon/fastmsgpack/internal"
@@ -9,24 +10,37 @@ import (
 
 var ErrVoid = internal.ErrVoid
 
+// ErrLimitExceeded is returned (wrapped) when the msgpack data exceeds the Limits given to WithLimits.
+var ErrLimitExceeded = internal.ErrLimitExceeded
+
+// ErrIntOverflow is returned when decoding a uint 64 that doesn't fit in an int with WithIntOverflowError.
+var ErrIntOverflow = internal.ErrIntOverflow
+
 // Decoder gives a low-level api for stepping through msgpack data.
 // Any []byte and string in return values might point into memory from the given data. Don't modify the input data until you're done with the return value.
 type Decoder struct {
+	// root is the data the Decoder was created with. data might point into other memory while we're inside an extension.
+	root        []byte
 	data        []byte
 	opt         internal.DecodeOptions
 	nestingInfo []nestingInfo
 	offset      int
+	// depth is the number of maps, arrays and wrapping extensions we're in, on top of opt.Depth. nestingInfo can't be used for this, as an entry is reused for the last child of its parent.
+	depth int
 }
 
 type nestingInfo struct {
 	returnTo          []byte
 	remainingElements int
 	end               int
+	// levels is the number of levels of depth this entry accounts for.
+	levels int
 }
 
 // NewDecoder initializes a new Decoder.
 func NewDecoder(data []byte, opts ...DecodeOption) *Decoder {
 	d := &Decoder{
+		root:        data,
 		data:        data,
 		nestingInfo: make([]nestingInfo, 0, 8),
 	}
@@ -44,6 +58,7 @@ func WithDict(dict *Dict) DecodeOption {
 			Strings:     dict.Strings,
 			Interfaces:  dict.interfaces,
 			JSONEncoded: &dict.jsonEncoded,
+			Origin:      dict,
 		}
 	}
 }
@@ -67,11 +82,65 @@ func WithInjection(field uint, msgpack []byte) DecodeOption {
 	}
 }
 
-// DecodeValue decodes the next value in the msgpack data. Return types are: nil, bool, int, float32, float64, string, []byte, time.Time, []any, map[string]any or Extension.
+// WithUint64 makes Decode, Resolve and DecodeValue return a uint64 for unsigned integers that don't fit in an int, rather than letting them wrap around to negative numbers.
+func WithUint64() DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.Uint64 = true
+	}
+}
+
+// WithIntOverflowError makes DecodeInt (and Decode and Resolve, unless WithUint64 is given) return ErrIntOverflow for unsigned integers that don't fit in an int, rather than letting them wrap around to negative numbers.
+func WithIntOverflowError() DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.IntOverflowError = true
+	}
+}
+
+// WithNonStringKeys makes Decode, Resolve and DecodeValue return a map[any]any for maps that have keys that aren't strings. Maps with only string keys are still returned as map[string]any.
+// Binary keys are returned as strings. Maps with keys that can't be used as a Go map key (like arrays) result in an error.
+func WithNonStringKeys() DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.NonStringKeys = true
+	}
+}
+
+// WithValidateUTF8 makes Validate check that all strings are valid UTF-8.
+func WithValidateUTF8() DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.ValidateUTF8 = true
+	}
+}
+
+// WithUnwrapLengthPrefix makes ResolveRaw return the value inside length-prefixed entries (extension 17) instead of the extension itself.
+func WithUnwrapLengthPrefix() DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.UnwrapLengthPrefix = true
+	}
+}
+
+// Limits restricts the resources used to decode untrusted input. Zero values mean unlimited.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of maps and arrays. Extensions that wrap another value (17, 18 and 20) count as a level too.
+	MaxDepth int
+	// MaxElements is the maximum number of elements in a single map or array.
+	MaxElements int
+	// MaxBytes is the maximum size of the msgpack data given to Decode, Resolve, Unmarshal, Canonical, JSONConverter.Convert, debug.Fdump and of a single value read by a StreamDecoder.
+	MaxBytes int
+}
+
+// WithLimits makes decoding return an error wrapping ErrLimitExceeded for data that exceeds the given limits.
+// Regardless of limits, maps and arrays that claim to have more elements than could fit in the data are rejected before allocating anything.
+func WithLimits(l Limits) DecodeOption {
+	return func(opt *internal.DecodeOptions) {
+		opt.Limits = internal.Limits(l)
+	}
+}
+
+// DecodeValue decodes the next value in the msgpack data. Return types are: nil, bool, int, float32, float64, string, []byte, time.Time, []any, map[string]any or Extension. With WithUint64 it can also return uint64 and with WithNonStringKeys also map[any]any.
 func (d *Decoder) DecodeValue() (any, error) {
-	v, c, err := decodeValue(d.data[d.offset:], d.opt)
+	v, c, err := decodeValue(d.data[d.offset:], d.nestedOptions())
 	if err != nil {
-		return nil, err
+		return nil, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -81,7 +150,7 @@ func (d *Decoder) DecodeValue() (any, error) {
 func (d *Decoder) DecodeString() (string, error) {
 	v, c, err := internal.DecodeString(d.data[d.offset:], d.opt)
 	if err != nil {
-		return "", err
+		return "", d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -91,7 +160,77 @@ func (d *Decoder) DecodeString() (string, error) {
 func (d *Decoder) DecodeInt() (int, error) {
 	v, c, err := internal.DecodeInt(d.data[d.offset:], d.opt)
 	if err != nil {
-		return 0, err
+		return 0, d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return v, nil
+}
+
+// DecodeIntChecked is like DecodeInt, but always returns an error wrapping ErrIntOverflow for a uint 64 that doesn't fit in an int, as if WithIntOverflowError was given.
+// The value isn't consumed in that case, so it can still be read with DecodeUint.
+func (d *Decoder) DecodeIntChecked() (int, error) {
+	opt := d.opt
+	opt.IntOverflowError = true
+	v, c, err := internal.DecodeInt(d.data[d.offset:], opt)
+	if err != nil {
+		return 0, d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return v, nil
+}
+
+// DecodeUint decodes the next value as an unsigned integer. Unlike DecodeInt it supports the full range of uint 64, and it returns an error for negative numbers.
+func (d *Decoder) DecodeUint() (uint64, error) {
+	v, c, err := internal.DecodeUint(d.data[d.offset:], d.opt)
+	if err != nil {
+		return 0, d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return v, nil
+}
+
+// DecodeMapKey decodes the next value as a map key. It returns a string for string and binary keys, and the same types as DecodeValue for other keys.
+// It returns an error for values that can't be used as a Go map key, like arrays. Use DecodeRaw to get the msgpack of the key instead.
+func (d *Decoder) DecodeMapKey() (any, error) {
+	v, c, err := decodeMapKey(d.data[d.offset:], d.opt)
+	if err != nil {
+		return nil, d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return v, nil
+}
+
+// DecodeBytes decodes the next value as bin data. Strings are accepted as well.
+func (d *Decoder) DecodeBytes() ([]byte, error) {
+	v, c, err := internal.DecodeBytes(d.data[d.offset:], d.opt)
+	if err != nil {
+		return nil, d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return v, nil
+}
+
+// DecodeNil consumes the next value, and returns an error if it isn't nil.
+func (d *Decoder) DecodeNil() error {
+	_, c, err := internal.DecodeNil(d.data[d.offset:], d.opt)
+	if err != nil {
+		return d.errorAt(err)
+	}
+	d.offset += c
+	d.consumedOne()
+	return nil
+}
+
+// DecodeExtension decodes the next value as an extension, without interpreting it. Extensions 17, 18 and 20 are handled transparently and extension 19 returns ErrVoid, like with the other Decode methods.
+func (d *Decoder) DecodeExtension() (Extension, error) {
+	v, c, err := decodeExtension(d.data[d.offset:], d.opt)
+	if err != nil {
+		return Extension{}, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -101,7 +240,7 @@ func (d *Decoder) DecodeInt() (int, error) {
 func (d *Decoder) DecodeFloat32() (float32, error) {
 	v, c, err := internal.DecodeFloat32(d.data[d.offset:], d.opt)
 	if err != nil {
-		return 0, err
+		return 0, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -111,7 +250,7 @@ func (d *Decoder) DecodeFloat32() (float32, error) {
 func (d *Decoder) DecodeFloat64() (float64, error) {
 	v, c, err := internal.DecodeFloat64(d.data[d.offset:], d.opt)
 	if err != nil {
-		return 0, err
+		return 0, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -121,7 +260,7 @@ func (d *Decoder) DecodeFloat64() (float64, error) {
 func (d *Decoder) DecodeBool() (bool, error) {
 	v, c, err := internal.DecodeBool(d.data[d.offset:], d.opt)
 	if err != nil {
-		return false, err
+		return false, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -131,7 +270,7 @@ func (d *Decoder) DecodeBool() (bool, error) {
 func (d *Decoder) DecodeTime() (time.Time, error) {
 	v, c, err := internal.DecodeTime(d.data[d.offset:], d.opt)
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -141,33 +280,71 @@ func (d *Decoder) DecodeTime() (time.Time, error) {
 func (d *Decoder) DecodeMapLen() (int, error) {
 	elements, c, end, stepIn, err := internal.DecodeMapLen(d.data[d.offset:], d.opt)
 	if err != nil {
-		return 0, err
+		return 0, d.errorAt(err)
+	}
+	levels := containerLevels(end, stepIn)
+	if err := d.checkContainer(elements, 2, c, levels, stepIn); err != nil {
+		return 0, d.errorAt(err)
 	}
 	if end > 0 {
 		end += d.offset
 	}
 	d.offset += c
-	d.consumingPush(elements*2, c, end, stepIn)
+	d.consumingPush(elements*2, c, end, levels, stepIn)
 	return elements, nil
 }
 
 func (d *Decoder) DecodeArrayLen() (int, error) {
 	elements, c, end, stepIn, err := internal.DecodeArrayLen(d.data[d.offset:], d.opt)
 	if err != nil {
-		return 0, err
+		return 0, d.errorAt(err)
+	}
+	levels := containerLevels(end, stepIn)
+	if err := d.checkContainer(elements, 1, c, levels, stepIn); err != nil {
+		return 0, d.errorAt(err)
 	}
 	if end > 0 {
 		end += d.offset
 	}
 	d.offset += c
-	d.consumingPush(elements, c, end, stepIn)
+	d.consumingPush(elements, c, end, levels, stepIn)
 	return elements, nil
 }
 
+// nestedOptions returns the options with Depth set to the current nesting depth, for decoding the next value separately.
+func (d *Decoder) nestedOptions() internal.DecodeOptions {
+	opt := d.opt
+	opt.Depth += d.depth
+	return opt
+}
+
+// containerLevels returns how many levels of depth a map or array header accounts for. A header wrapped in an extension (which gives us an end or data to step into) counts as two.
+func containerLevels(end int, stepIn []byte) int {
+	if end > 0 || stepIn != nil {
+		return 2
+	}
+	return 1
+}
+
+// checkContainer verifies the limits for a map or array header that was just decoded (but not yet consumed).
+func (d *Decoder) checkContainer(elements, perElement, consume, levels int, stepIn []byte) error {
+	opt := d.nestedOptions()
+	for i := 0; levels > i; i++ {
+		if err := opt.Descend(); err != nil {
+			return err
+		}
+	}
+	remaining := len(d.data) - d.offset - consume
+	if stepIn != nil {
+		remaining = len(stepIn) - consume
+	}
+	return opt.CheckElements(elements, perElement, remaining)
+}
+
 func (d *Decoder) Skip() error {
 	c, err := internal.ValueLength(d.data[d.offset:])
 	if err != nil {
-		return err
+		return d.errorAt(err)
 	}
 	d.offset += c
 	d.consumedOne()
@@ -180,7 +357,7 @@ func (d *Decoder) DecodeRaw() ([]byte, error) {
 	b := d.data[d.offset:]
 	c, err := internal.ValueLength(b)
 	if err != nil {
-		return nil, err
+		return nil, d.errorAt(err)
 	}
 	b = b[:c]
 	d.offset += c
@@ -196,8 +373,9 @@ func (d *Decoder) DecodeLazy() (*Decoder, error) {
 		return nil, err
 	}
 	return &Decoder{
+		root: b,
 		data: b,
-		opt:  d.opt,
+		opt:  d.nestedOptions(),
 	}, nil
 }
 
@@ -211,6 +389,7 @@ func (d *Decoder) Break() error {
 	ni := d.nestingInfo[l]
 	d.nestingInfo[l].returnTo = nil // don't retain the pointer
 	d.nestingInfo = d.nestingInfo[:l]
+	d.depth -= ni.levels
 	switch {
 	case ni.returnTo != nil:
 		d.data = ni.returnTo
@@ -221,7 +400,7 @@ func (d *Decoder) Break() error {
 	}
 	c, err := internal.SkipMultiple(d.data, d.offset, ni.remainingElements)
 	if err != nil {
-		return err
+		return d.errorAt(err)
 	}
 	d.offset = c
 	return nil
@@ -239,17 +418,34 @@ func (d *Decoder) PeekRaw() ([]byte, error) {
 	b := d.data[d.offset:]
 	c, err := internal.ValueLength(b)
 	if err != nil {
-		return nil, err
+		return nil, d.errorAt(err)
 	}
 	return b[:c], nil
 }
 
 // Reset this decoder for use on another piece of msgpack (with the same settings).
 func (d *Decoder) Reset(data []byte) {
+	d.root = data
 	d.data = data
 	clear(d.nestingInfo)
 	d.nestingInfo = d.nestingInfo[:0]
 	d.offset = 0
+	d.depth = 0
+}
+
+// errorAt returns err as a *DecodeError for the value at the current position.
+func (d *Decoder) errorAt(err error) error {
+	if base, ok := internal.OffsetIn(d.root, d.data); ok {
+		return decodeErrorAt(err, base+d.offset, "")
+	}
+	// We're inside injected data. Point to the end of the injection extension instead.
+	for i := len(d.nestingInfo) - 1; i >= 0; i-- {
+		ni := d.nestingInfo[i]
+		if base, ok := internal.OffsetIn(d.root, ni.returnTo); ok {
+			return decodeErrorAt(injectedDecodeError(err), base+ni.end, "")
+		}
+	}
+	return decodeErrorAt(injectedDecodeError(err), 0, "")
 }
 
 func (d *Decoder) consumedOne() {
@@ -265,11 +461,12 @@ func (d *Decoder) consumedOne() {
 			d.offset = d.nestingInfo[l].end
 			d.nestingInfo[l].returnTo = nil // don't retain the pointer
 		}
+		d.depth -= d.nestingInfo[l].levels
 		d.nestingInfo = d.nestingInfo[:l]
 	}
 }
 
-func (d *Decoder) consumingPush(elements, consume, end int, stepIn []byte) {
+func (d *Decoder) consumingPush(elements, consume, end, levels int, stepIn []byte) {
 	// invariant: If stepIn != nil, end is known (and not 0)
 	if elements == 0 {
 		d.consumedOne()
@@ -282,7 +479,9 @@ func (d *Decoder) consumingPush(elements, consume, end int, stepIn []byte) {
 	add := nestingInfo{
 		remainingElements: elements,
 		end:               end,
+		levels:            levels,
 	}
+	d.depth += levels
 	if stepIn != nil {
 		add.returnTo = d.data
 		add.end = end
@@ -300,23 +499,32 @@ func (d *Decoder) consumingPush(elements, consume, end int, stepIn []byte) {
 	} else if d.nestingInfo[l].returnTo != nil {
 		// Retain our parent's returnTo and end values, because we are the last child of our parent our end is their end.
 		d.nestingInfo[l].remainingElements = add.remainingElements
+		d.nestingInfo[l].levels += levels
 	} else {
+		// We replace our parent, which is done once we are, but we're still nested in it until then.
+		add.levels += d.nestingInfo[l].levels
 		d.nestingInfo[l] = add
 	}
 }
 
 func decodeValue_array(data []byte, offset, num int, opt internal.DecodeOptions) ([]any, int, error) {
+	if err := opt.Descend(); err != nil {
+		return nil, 0, err
+	}
+	if err := opt.CheckElements(num, 1, len(data)-offset); err != nil {
+		return nil, 0, err
+	}
 	ret := make([]any, num)
 	var voided int
 	for i := range ret {
 		v, c, err := decodeValue(data[offset:], opt)
+		if err != nil && err != ErrVoid {
+			return nil, 0, decodeErrorAt(err, offset, internal.IndexSegment(i))
+		}
 		offset += c
-		if err != nil {
-			if err == ErrVoid {
-				voided++
-				continue
-			}
-			return nil, 0, err
+		if err == ErrVoid {
+			voided++
+			continue
 		}
 		ret[i-voided] = v
 	}
@@ -324,19 +532,67 @@ func decodeValue_array(data []byte, offset, num int, opt internal.DecodeOptions)
 	return ret, offset, nil
 }
 
-func decodeValue_map(data []byte, offset, num int, opt internal.DecodeOptions) (map[string]any, int, error) {
+func decodeValue_map(data []byte, offset, num int, opt internal.DecodeOptions) (any, int, error) {
+	if err := opt.Descend(); err != nil {
+		return nil, 0, err
+	}
+	if err := opt.CheckElements(num, 2, len(data)-offset); err != nil {
+		return nil, 0, err
+	}
 	ret := make(map[string]any, num)
 	for num > 0 {
 		num--
 		k, c, err := internal.DecodeString(data[offset:], opt)
 		if err != nil {
 			if err == ErrVoid {
-				offset, err = internal.SkipMultiple(data, offset, 2)
+				var skipped int
+				skipped, err = internal.SkipMultiple(data, offset, 2)
+				if err == nil {
+					offset = skipped
+					continue
+				}
+			} else if opt.NonStringKeys {
+				return decodeValue_anyMap(data, offset, num+1, ret, opt)
+			}
+			return nil, 0, decodeErrorAt(err, offset, "")
+		}
+		offset += c
+		v, c, err := decodeValue(data[offset:], opt)
+		if err != nil {
+			if err == ErrVoid {
+				c, err = internal.ValueLength(data[offset:])
+				if err == nil {
+					offset += c
+					continue
+				}
+			}
+			return nil, 0, decodeErrorAt(err, offset, k)
+		}
+		ret[k] = v
+		offset += c
+	}
+	return ret, offset, nil
+}
+
+// decodeValue_anyMap continues decoding a map after decodeValue_map found a non-string key. The entries decoded so far are passed in as partial.
+func decodeValue_anyMap(data []byte, offset, num int, partial map[string]any, opt internal.DecodeOptions) (map[any]any, int, error) {
+	ret := make(map[any]any, len(partial)+num)
+	for k, v := range partial {
+		ret[k] = v
+	}
+	for num > 0 {
+		num--
+		k, c, err := decodeMapKey(data[offset:], opt)
+		if err != nil {
+			if err == ErrVoid {
+				var skipped int
+				skipped, err = internal.SkipMultiple(data, offset, 2)
 				if err == nil {
+					offset = skipped
 					continue
 				}
 			}
-			return nil, 0, err
+			return nil, 0, decodeErrorAt(err, offset, "")
 		}
 		offset += c
 		v, c, err := decodeValue(data[offset:], opt)
@@ -348,7 +604,7 @@ func decodeValue_map(data []byte, offset, num int, opt internal.DecodeOptions) (
 					continue
 				}
 			}
-			return nil, 0, err
+			return nil, 0, decodeErrorAt(err, offset, fmt.Sprint(k))
 		}
 		ret[k] = v
 		offset += c
@@ -356,7 +612,57 @@ func decodeValue_map(data []byte, offset, num int, opt internal.DecodeOptions) (
 	return ret, offset, nil
 }
 
+func decodeMapKey(data []byte, opt internal.DecodeOptions) (any, int, error) {
+	k, c, err := decodeValue(data, opt)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch v := k.(type) {
+	case []byte:
+		return internal.UnsafeStringCast(v), c, nil
+	case []any, map[string]any, map[any]any, Extension:
+		return nil, 0, fmt.Errorf("fastmsgpack: can't use %T as a map key", k)
+	}
+	return k, c, nil
+}
+
+func decodeExtension_ext(data []byte, extType int8, opt internal.DecodeOptions) (Extension, error) {
+	switch extType {
+	case 17: // Length-prefixed entry
+		ret, _, err := decodeExtension(data, opt)
+		return ret, err
+
+	case 18: // Flavor pick
+		j, err := internal.DecodeFlavorPick(data, opt)
+		if err != nil {
+			return Extension{}, err
+		}
+		ret, _, err := decodeExtension(data[j:], opt)
+		return ret, err
+
+	case 19:
+		return Extension{}, ErrVoid
+
+	case 20: // Injection
+		b, err := internal.DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return Extension{}, err
+		}
+		ret, _, err := decodeExtension(b, opt)
+		return ret, err
+
+	default:
+		return Extension{Type: extType, Data: data}, nil
+	}
+}
+
 func decodeValue_ext(data []byte, extType int8, opt internal.DecodeOptions) (any, error) {
+	switch extType {
+	case 17, 18, 20:
+		if err := opt.Descend(); err != nil {
+			return nil, err
+		}
+	}
 	switch extType {
 	case -1: // Timestamp
 		return internal.DecodeTimestamp(data)
@@ -378,7 +684,7 @@ func decodeValue_ext(data []byte, extType int8, opt internal.DecodeOptions) (any
 			return nil, err
 		}
 		ret, _, err := decodeValue(data[j:], opt)
-		return ret, err
+		return ret, shiftDecodeError(err, j)
 
 	case 19:
 		return nil, ErrVoid
@@ -389,7 +695,7 @@ func decodeValue_ext(data []byte, extType int8, opt internal.DecodeOptions) (any
 			return nil, err
 		}
 		ret, _, err := decodeValue(b, opt)
-		return ret, err
+		return ret, injectedDecodeError(err)
 
 	default:
 		return Extension{Type: extType, Data: data}, nil
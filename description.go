@@ -0,0 +1,229 @@
+package fastmsgpack
+
+import (
+	"errors"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// ResolverDescription is a serializable description of a Resolver, as returned by Description.
+// It can be encoded with Encode (or any other encoder) and turned back into a Resolver with NewResolverFromDescription, so Resolvers can be shared between services.
+type ResolverDescription struct {
+	// Fields has a description of every field, in the order Resolve returns them.
+	Fields []FieldDescription `msgpack:"fields"`
+	// Options describes the options given to NewResolver, except for WithDict.
+	Options *OptionsDescription `msgpack:"options,omitempty"`
+}
+
+// FieldDescription describes one field of a Resolver.
+type FieldDescription struct {
+	// Path is the field as given to NewResolver, or the path to the array for array resolvers.
+	Path string `msgpack:"path"`
+	// Type is set for typed fields: "int", "float64", "string", "bytes", "bool" or "time" for AddInt, AddFloat64, AddString, AddBytes, AddBool and AddTime.
+	Type string `msgpack:"type,omitempty"`
+	// Default is the msgpack encoded default of a typed field.
+	Default []byte `msgpack:"default,omitempty"`
+	// Required is set for fields marked with Require.
+	Required bool `msgpack:"required,omitempty"`
+	// Subresolver describes the subresolver of an array resolver.
+	Subresolver *ResolverDescription `msgpack:"sub,omitempty"`
+	// Filter is the name of the filter of an array resolver, as given to AddNamedFilteredArrayResolver.
+	Filter string `msgpack:"filter,omitempty"`
+}
+
+// OptionsDescription describes the DecodeOptions of a Resolver.
+type OptionsDescription struct {
+	FlavorSelectors    map[uint]uint   `msgpack:"flavorSelectors,omitempty"`
+	Injections         map[uint][]byte `msgpack:"injections,omitempty"`
+	Uint64             bool            `msgpack:"uint64,omitempty"`
+	IntOverflowError   bool            `msgpack:"intOverflowError,omitempty"`
+	NonStringKeys      bool            `msgpack:"nonStringKeys,omitempty"`
+	ValidateUTF8       bool            `msgpack:"validateUTF8,omitempty"`
+	UnwrapLengthPrefix bool            `msgpack:"unwrapLengthPrefix,omitempty"`
+	Limits             *Limits         `msgpack:"limits,omitempty"`
+}
+
+// Description returns a serializable description of the Resolver, which NewResolverFromDescription turns back into an equivalent Resolver.
+// Filters are described by name, so it returns an error for array resolvers added with AddFilteredArrayResolver; use AddNamedFilteredArrayResolver instead.
+// The dict given with WithDict and CompileForDict aren't part of the description.
+func (r *Resolver) Description() (ResolverDescription, error) {
+	fields, subs := r.Describe()
+	d, err := describeFields(r.numFields, fields, subs)
+	if err != nil {
+		return ResolverDescription{}, err
+	}
+	for _, tf := range r.typedFields {
+		def, err := Encode(nil, tf.def)
+		if err != nil {
+			return ResolverDescription{}, err
+		}
+		d.Fields[tf.index].Type = tf.kind
+		d.Fields[tf.index].Default = def
+	}
+	for _, rf := range r.required {
+		d.Fields[rf.index].Required = true
+	}
+	var opt internal.DecodeOptions
+	for _, o := range r.decodeOptions {
+		o(&opt)
+	}
+	d.Options = describeOptions(opt)
+	return d, nil
+}
+
+// describeFields turns the results of Describe into FieldDescriptions.
+func describeFields(numFields int, fields []string, subs map[string]SubresolverDescription) (ResolverDescription, error) {
+	d := ResolverDescription{Fields: make([]FieldDescription, numFields)}
+	for i, f := range fields {
+		d.Fields[i].Path = f
+	}
+	for path, sd := range subs {
+		if sd.filtered && sd.Filter == "" {
+			return ResolverDescription{}, errors.New("Description: can't describe the unnamed filter of the array resolver for " + path)
+		}
+		numSubFields := len(sd.Fields)
+		for _, s := range sd.Subresolvers {
+			numSubFields = max(numSubFields, s.Index+1)
+		}
+		sub, err := describeFields(numSubFields, sd.Fields, sd.Subresolvers)
+		if err != nil {
+			return ResolverDescription{}, err
+		}
+		d.Fields[sd.Index] = FieldDescription{Path: path, Subresolver: &sub, Filter: sd.Filter}
+	}
+	return d, nil
+}
+
+func describeOptions(opt internal.DecodeOptions) *OptionsDescription {
+	od := OptionsDescription{
+		FlavorSelectors:    opt.FlavorSelectors,
+		Injections:         opt.Injections,
+		Uint64:             opt.Uint64,
+		IntOverflowError:   opt.IntOverflowError,
+		NonStringKeys:      opt.NonStringKeys,
+		ValidateUTF8:       opt.ValidateUTF8,
+		UnwrapLengthPrefix: opt.UnwrapLengthPrefix,
+	}
+	if opt.Limits != (internal.Limits{}) {
+		l := Limits(opt.Limits)
+		od.Limits = &l
+	}
+	if len(od.FlavorSelectors) == 0 && len(od.Injections) == 0 && !od.Uint64 && !od.IntOverflowError && !od.NonStringKeys && !od.ValidateUTF8 && !od.UnwrapLengthPrefix && od.Limits == nil {
+		return nil
+	}
+	return &od
+}
+
+// decodeOptions returns the options that were described.
+func (od *OptionsDescription) decodeOptions() []DecodeOption {
+	if od == nil {
+		return nil
+	}
+	var opts []DecodeOption
+	for field, value := range od.FlavorSelectors {
+		opts = append(opts, WithFlavorSelector(field, value))
+	}
+	for field, msgpack := range od.Injections {
+		opts = append(opts, WithInjection(field, msgpack))
+	}
+	if od.Uint64 {
+		opts = append(opts, WithUint64())
+	}
+	if od.IntOverflowError {
+		opts = append(opts, WithIntOverflowError())
+	}
+	if od.NonStringKeys {
+		opts = append(opts, WithNonStringKeys())
+	}
+	if od.ValidateUTF8 {
+		opts = append(opts, WithValidateUTF8())
+	}
+	if od.UnwrapLengthPrefix {
+		opts = append(opts, WithUnwrapLengthPrefix())
+	}
+	if od.Limits != nil {
+		opts = append(opts, WithLimits(*od.Limits))
+	}
+	return opts
+}
+
+// NewResolverFromDescription creates a Resolver from a description returned by Description.
+// The filters of array resolvers are looked up by name in filters. The given options are added to the described ones, which is needed for WithDict.
+func NewResolverFromDescription(d ResolverDescription, filters map[string]func(found []any) bool, opts ...DecodeOption) (*Resolver, error) {
+	r := &Resolver{decodeOptions: append(d.Options.decodeOptions(), opts...)}
+	if err := r.addDescribedFields(d.Fields, filters); err != nil {
+		return nil, err
+	}
+	var required []int
+	for i, f := range d.Fields {
+		if f.Required {
+			required = append(required, i)
+		}
+	}
+	if len(required) > 0 {
+		if err := r.Require(required...); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Resolver) addDescribedFields(fields []FieldDescription, filters map[string]func(found []any) bool) error {
+	for _, f := range fields {
+		var err error
+		switch {
+		case f.Subresolver != nil:
+			sub := &Resolver{}
+			if err := sub.addDescribedFields(f.Subresolver.Fields, filters); err != nil {
+				return err
+			}
+			var keep func(found []any) bool
+			if f.Filter != "" {
+				if keep = filters[f.Filter]; keep == nil {
+					return errors.New("NewResolverFromDescription: unknown filter: " + f.Filter)
+				}
+			}
+			_, err = r.addArrayResolver(f.Path, sub, f.Filter, keep)
+		case f.Type != "":
+			err = r.addDescribedTypedField(f)
+		default:
+			if err = r.addField(f.Path, r.numFields); err == nil {
+				r.numFields++
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) addDescribedTypedField(f FieldDescription) error {
+	switch f.Type {
+	case "int":
+		return addDescribedTypedField(f, r.AddInt)
+	case "float64":
+		return addDescribedTypedField(f, r.AddFloat64)
+	case "string":
+		return addDescribedTypedField(f, r.AddString)
+	case "bytes":
+		return addDescribedTypedField(f, r.AddBytes)
+	case "bool":
+		return addDescribedTypedField(f, r.AddBool)
+	case "time":
+		return addDescribedTypedField(f, r.AddTime)
+	default:
+		return errors.New("NewResolverFromDescription: unknown type " + f.Type + " for field " + f.Path)
+	}
+}
+
+func addDescribedTypedField[T any](f FieldDescription, add func(field string, def T) (Field[T], error)) error {
+	var def T
+	if len(f.Default) > 0 {
+		if err := Unmarshal(f.Default, &def); err != nil {
+			return err
+		}
+	}
+	_, err := add(f.Path, def)
+	return err
+}
@@ -0,0 +1,104 @@
+package fastmsgpack
+
+import "github.com/hexon/fastmsgpack/internal"
+
+// internedInterests is a map[string]any compiled against a dict by CompileForDict.
+// byIndex has the interest for every entry of the dict that is one of the keys, so interned keys can be looked up by their index.
+type internedInterests struct {
+	keys    map[string]any
+	byIndex []any
+}
+
+// CompileForDict speeds up resolving data with map keys interned in the given dict (see EncodeOptions.Dict). Such keys are matched by their index in the dict, rather than by decoding and hashing their string.
+// It only applies when the data is decoded with the same *Dict, given with WithDict to NewResolver or Resolve. Keys that aren't interned are matched as usual.
+// Adding fields to the Resolver undoes the compilation, so call it after the Resolver is complete. Passing nil undoes it as well. It must not be called concurrently with resolving.
+func (r *Resolver) CompileForDict(dict *Dict) {
+	if dict == nil {
+		r.compiled, r.compiledDict = nil, nil
+		return
+	}
+	positions := make(map[string][]int, len(dict.Strings))
+	for i, s := range dict.Strings {
+		positions[s] = append(positions[s], i)
+	}
+	r.compiled = compileInterests(r.rootInterests(), positions)
+	r.compiledDict = dict
+}
+
+// compileInterests returns a copy of the interests with every map[string]any replaced by an *internedInterests. positions has the indexes in the dict of every string.
+func compileInterests(interest any, positions map[string][]int) any {
+	switch x := interest.(type) {
+	case map[string]any:
+		ret := &internedInterests{keys: make(map[string]any, len(x))}
+		for k, v := range x {
+			c := compileInterests(v, positions)
+			ret.keys[k] = c
+			for _, i := range positions[k] {
+				if i >= len(ret.byIndex) {
+					ret.byIndex = append(ret.byIndex, make([]any, i+1-len(ret.byIndex))...)
+				}
+				ret.byIndex[i] = c
+			}
+		}
+		return ret
+	case *arrayInterests:
+		ret := &arrayInterests{indexes: make(map[int]any, len(x.indexes))}
+		for i, v := range x.indexes {
+			ret.indexes[i] = compileInterests(v, positions)
+		}
+		if x.wildcard != nil {
+			ret.wildcard = &wildcardInterests{
+				interests:    compileInterests(x.wildcard.interests, positions),
+				destinations: x.wildcard.destinations,
+			}
+		}
+		return ret
+	case *nestedInterests:
+		return &nestedInterests{
+			parent:   compileInterests(x.parent, positions),
+			children: compileInterests(x.children, positions),
+		}
+	case subresolver:
+		x.interests = compileInterests(x.interests, positions)
+		return x
+	default:
+		return interest
+	}
+}
+
+// usedBy returns whether the options use this dict, as given with WithDict.
+func (d *Dict) usedBy(opt internal.DecodeOptions) bool {
+	return opt.Dict != nil && opt.Dict.Origin == any(d)
+}
+
+// decodeInternedKey consumes the next value if it is a string interned in the dict of the Decoder, and returns its index in the dict.
+// Anything else (including indexes outside of the dict) is left for the regular decoding functions.
+func (d *Decoder) decodeInternedKey() (uint, bool) {
+	data := d.data[d.offset:]
+	if len(data) < 3 || int8(data[1]) != -128 || d.opt.Dict == nil {
+		return 0, false
+	}
+	var size int
+	switch data[0] {
+	case 0xd4:
+		size = 1
+	case 0xd5:
+		size = 2
+	case 0xd6:
+		size = 4
+	case 0xd7:
+		size = 8
+	default:
+		return 0, false
+	}
+	if len(data) < 2+size {
+		return 0, false
+	}
+	n, ok := internal.DecodeBytesToUint(data[2 : 2+size])
+	if !ok || n >= uint(len(d.opt.Dict.Strings)) {
+		return 0, false
+	}
+	d.offset += 2 + size
+	d.consumedOne()
+	return n, true
+}
@@ -120,6 +120,11 @@ func (o EncodeOptions) Encode(dst []byte, v any) ([]byte, error) {
 	case Extension:
 		return v.AppendMsgpack(dst)
 
+	case interface {
+		AppendMsgpackWithOptions([]byte, EncodeOptions) ([]byte, error)
+	}:
+		return v.AppendMsgpackWithOptions(dst, o)
+
 	case interface{ AppendMsgpack([]byte) ([]byte, error) }:
 		return v.AppendMsgpack(dst)
 
@@ -131,47 +136,86 @@ func (o EncodeOptions) Encode(dst []byte, v any) ([]byte, error) {
 		return append(dst, b...), nil
 
 	default:
-		rv := reflect.ValueOf(v)
-		switch rv.Kind() {
-		case reflect.Pointer:
-			if rv.IsNil() {
-				return o.EncodeNil(dst), nil
-			}
-			return o.Encode(dst, rv.Elem().Interface())
+		return o.encodeReflect(dst, reflect.ValueOf(v))
+	}
+}
+
+// encodeReflect encodes types that aren't handled by Encode's type switch, like structs, pointers, maps and slices of other types and named basic types.
+func (o EncodeOptions) encodeReflect(dst []byte, rv reflect.Value) ([]byte, error) {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return o.EncodeNil(dst), nil
+		}
+		return encodeInterface(o, dst, rv.Elem())
 
-		case reflect.Map:
-			dst, err := internal.AppendMapLen(dst, rv.Len())
+	case reflect.Map:
+		dst, err := internal.AppendMapLen(dst, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			dst, err = encodeInterface(o, dst, iter.Key())
 			if err != nil {
 				return nil, err
 			}
-			iter := rv.MapRange()
-			for iter.Next() {
-				dst, err = o.Encode(dst, iter.Key().Interface())
-				if err != nil {
-					return nil, err
-				}
-				dst, err = o.Encode(dst, iter.Value().Interface())
-				if err != nil {
-					return nil, err
-				}
-			}
-			return dst, nil
-		case reflect.Slice, reflect.Array:
-			dst, err := internal.AppendArrayLen(dst, rv.Len())
+			dst, err = encodeInterface(o, dst, iter.Value())
 			if err != nil {
 				return nil, err
 			}
-			for i := 0; rv.Len() > i; i++ {
-				dst, err = o.Encode(dst, rv.Index(i).Interface())
-				if err != nil {
-					return nil, err
-				}
+		}
+		return dst, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Named byte slices (like json.RawMessage) are binary, just like []byte.
+			return o.EncodeBytes(dst, rv.Bytes())
+		}
+		dst, err := internal.AppendArrayLen(dst, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; rv.Len() > i; i++ {
+			dst, err = encodeInterface(o, dst, rv.Index(i))
+			if err != nil {
+				return nil, err
 			}
-			return dst, nil
-
-		default:
-			return nil, fmt.Errorf("fastmsgpack.Encode: don't know how to encode %T", v)
 		}
+		return dst, nil
+	case reflect.Struct:
+		return o.encodeStruct(dst, rv)
+
+	case reflect.Bool:
+		return o.EncodeBool(dst, rv.Bool()), nil
+	case reflect.String:
+		return o.EncodeString(dst, rv.String())
+	case reflect.Float32:
+		return o.EncodeFloat32(dst, float32(rv.Float())), nil
+	case reflect.Float64:
+		return o.EncodeFloat64(dst, rv.Float()), nil
+	case reflect.Int:
+		return appendCompactInt(dst, int(rv.Int())), nil
+	case reflect.Int8:
+		return o.Encode(dst, int8(rv.Int()))
+	case reflect.Int16:
+		return o.Encode(dst, int16(rv.Int()))
+	case reflect.Int32:
+		return o.Encode(dst, int32(rv.Int()))
+	case reflect.Int64:
+		return o.Encode(dst, rv.Int())
+	case reflect.Uint:
+		return appendCompactUint(dst, uint(rv.Uint())), nil
+	case reflect.Uint8:
+		return o.Encode(dst, uint8(rv.Uint()))
+	case reflect.Uint16:
+		return o.Encode(dst, uint16(rv.Uint()))
+	case reflect.Uint32:
+		return o.Encode(dst, uint32(rv.Uint()))
+	case reflect.Uint64:
+		return o.Encode(dst, rv.Uint())
+
+	default:
+		return nil, fmt.Errorf("fastmsgpack.Encode: don't know how to encode %s", rv.Type())
 	}
 }
 
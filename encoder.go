@@ -0,0 +1,210 @@
+package fastmsgpack
+
+import (
+	"errors"
+	"io"
+)
+
+const encoderFlushSize = 64 * 1024
+
+// Encoder writes msgpack to an io.Writer. It buffers internally, so you must call Flush when you're done.
+//
+// Preliminary length headers (see PreliminaryMapLen) are rewritten in place if the writer is an io.WriteSeeker.
+// Otherwise the Encoder keeps everything after the first unfinalized header in memory until all headers are finalized.
+type Encoder struct {
+	w       io.Writer
+	seeker  io.WriteSeeker
+	opt     EncodeOptions
+	buf     []byte
+	flushed int64 // number of bytes written to w, buf starts at this offset.
+	base    int64 // offset in the seeker where we started writing.
+	pending int   // number of unfinalized length headers that are still in buf.
+	err     error
+}
+
+// NewEncoder creates an Encoder that writes to w with the given options.
+func NewEncoder(w io.Writer, opt EncodeOptions) *Encoder {
+	e := &Encoder{
+		w:   w,
+		opt: opt,
+	}
+	e.seeker, _ = w.(io.WriteSeeker)
+	return e
+}
+
+// Encode writes the msgpack representation of v. See EncodeOptions.Encode.
+func (e *Encoder) Encode(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+	b, err := e.opt.Encode(e.buf, v)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	return e.maybeFlush()
+}
+
+// EncodeString writes a string, interning it if it's in the dict.
+func (e *Encoder) EncodeString(v string) error {
+	if e.err != nil {
+		return e.err
+	}
+	b, err := e.opt.EncodeString(e.buf, v)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	return e.maybeFlush()
+}
+
+// EncodeMapLen writes a map header that indicates the next n key+values are part of this map.
+func (e *Encoder) EncodeMapLen(n int) error {
+	if e.err != nil {
+		return e.err
+	}
+	b, err := e.opt.EncodeMapLen(e.buf, n)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	return e.maybeFlush()
+}
+
+// EncodeArrayLen writes an array header that indicates the next n values are part of this array.
+func (e *Encoder) EncodeArrayLen(n int) error {
+	if e.err != nil {
+		return e.err
+	}
+	b, err := e.opt.EncodeArrayLen(e.buf, n)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	return e.maybeFlush()
+}
+
+// EncodeRaw writes the given msgpack data as is.
+func (e *Encoder) EncodeRaw(msgpack []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.buf = append(e.buf, msgpack...)
+	return e.maybeFlush()
+}
+
+// PreliminaryMapLen is like EncodeMapLen in case you don't know the exact length yet.
+// You're required to call .Finalize() on the returned header once you know the exact number of key-values written.
+func (e *Encoder) PreliminaryMapLen(max int) (*EncoderLengthHeader, error) {
+	return e.preliminaryLen(max, true)
+}
+
+// PreliminaryArrayLen is like EncodeArrayLen in case you don't know the exact length yet.
+// You're required to call .Finalize() on the returned header once you know the exact number of values written.
+func (e *Encoder) PreliminaryArrayLen(max int) (*EncoderLengthHeader, error) {
+	return e.preliminaryLen(max, false)
+}
+
+func (e *Encoder) preliminaryLen(max int, isMap bool) (*EncoderLengthHeader, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	var p PreliminaryLengthHeader
+	e.buf, p = appendPreliminaryLen(e.buf, max, isMap)
+	h := &EncoderLengthHeader{
+		e:      e,
+		pos:    e.flushed + int64(p.offset),
+		header: p,
+	}
+	e.pending++
+	return h, nil
+}
+
+// Flush writes all buffered data to the underlying writer.
+// It returns an error if there are unfinalized length headers and the writer isn't an io.WriteSeeker.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.pending > 0 && e.seeker == nil {
+		return errors.New("fastmsgpack.Encoder.Flush: can't flush with unfinalized length headers on a writer that can't seek")
+	}
+	return e.flush()
+}
+
+func (e *Encoder) maybeFlush() error {
+	if len(e.buf) < encoderFlushSize || (e.pending > 0 && e.seeker == nil) {
+		return nil
+	}
+	return e.flush()
+}
+
+func (e *Encoder) flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	if e.flushed == 0 && e.seeker != nil {
+		e.base, e.err = e.seeker.Seek(0, io.SeekCurrent)
+		if e.err != nil {
+			return e.err
+		}
+	}
+	n, err := e.w.Write(e.buf)
+	e.flushed += int64(n)
+	if err != nil {
+		e.err = err
+		return err
+	}
+	e.buf = e.buf[:0]
+	// Headers that were in the buffer now need to be rewritten through the seeker.
+	e.pending = 0
+	return nil
+}
+
+// EncoderLengthHeader is a length header written by Encoder.PreliminaryMapLen or Encoder.PreliminaryArrayLen.
+type EncoderLengthHeader struct {
+	e         *Encoder
+	pos       int64
+	header    PreliminaryLengthHeader
+	finalized bool
+}
+
+// Finalize stores the actual number of elements in this map/array length header.
+// It can only be called once per header.
+func (h *EncoderLengthHeader) Finalize(num int) error {
+	e := h.e
+	if e.err != nil {
+		return e.err
+	}
+	if h.finalized {
+		return errors.New("fastmsgpack.EncoderLengthHeader.Finalize: header was already finalized")
+	}
+	if h.pos >= e.flushed {
+		p := h.header
+		p.offset = int(h.pos - e.flushed)
+		if err := p.Finalize(e.buf, num); err != nil {
+			return err
+		}
+		h.finalized = true
+		e.pending--
+		return e.maybeFlush()
+	}
+	b, p := appendPreliminaryLen(nil, h.header.max, h.header.isMap)
+	if err := p.Finalize(b, num); err != nil {
+		return err
+	}
+	if _, err := e.seeker.Seek(e.base+h.pos, io.SeekStart); err != nil {
+		e.err = err
+		return err
+	}
+	if _, err := e.seeker.Write(b); err != nil {
+		e.err = err
+		return err
+	}
+	h.finalized = true
+	if _, err := e.seeker.Seek(e.base+e.flushed, io.SeekStart); err != nil {
+		e.err = err
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package fastmsgpack
+
+import (
+	"fmt"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// DecodeError is returned for malformed msgpack data. It tells where in the data the problem was found.
+// ErrVoid is never wrapped in a DecodeError, as it isn't a problem with the data.
+type DecodeError struct {
+	// Offset is the byte offset in the data of the value that couldn't be decoded.
+	// Errors inside data injected with WithInjection point at the injection extension.
+	Offset int
+	// Path is the path of map keys and array indexes to the value, like "person.addresses[3].street".
+	// The Decoder doesn't know the keys of the maps it is in, so for its errors the Path is relative to the value being decoded.
+	Path string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("fastmsgpack: %v (at offset %d)", e.Err, e.Offset)
+	}
+	return fmt.Sprintf("fastmsgpack: %v (at offset %d, %s)", e.Err, e.Offset, e.Path)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeErrorAt turns err into a *DecodeError for the value at the given offset, or moves an existing *DecodeError by offset. The segment is prepended to its Path.
+// nil and ErrVoid are returned as is.
+func decodeErrorAt(err error, offset int, segment string) error {
+	if err == nil || err == ErrVoid {
+		return err
+	}
+	de, ok := err.(*DecodeError)
+	if !ok {
+		return &DecodeError{Offset: offset, Path: segment, Err: err}
+	}
+	de.Offset += offset
+	de.Path = internal.PrependPath(segment, de.Path)
+	return de
+}
+
+// shiftDecodeError moves a *DecodeError by offset. Other errors are returned as is, so the caller can attribute them to the value it was decoding.
+func shiftDecodeError(err error, offset int) error {
+	if de, ok := err.(*DecodeError); ok {
+		de.Offset += offset
+	}
+	return err
+}
+
+// injectedDecodeError makes a *DecodeError from within injected data point at the start of the injection, as offsets in the injected data mean nothing in the original data.
+func injectedDecodeError(err error) error {
+	if de, ok := err.(*DecodeError); ok {
+		de.Offset = 0
+	}
+	return err
+}
+
+// keySegment returns the path segment for the msgpack encoded map key. It's only used for errors, so it doesn't need to be fast.
+func keySegment(key []byte, opt internal.DecodeOptions) string {
+	if k, _, err := decodeMapKey(key, opt); err == nil {
+		return fmt.Sprint(k)
+	}
+	return internal.DescribeValue(key)
+}
+
+// MissingFieldError is returned when resolving data that lacks a field marked with Require.
+type MissingFieldError struct {
+	// Field is the path of the missing field, like "person.addresses[0].street".
+	Field string
+	// Void is true if the field was present, but void.
+	Void bool
+}
+
+func (e *MissingFieldError) Error() string {
+	if e.Void {
+		return fmt.Sprintf("fastmsgpack: required field %s is void", e.Field)
+	}
+	return fmt.Sprintf("fastmsgpack: required field %s is missing", e.Field)
+}
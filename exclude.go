@@ -0,0 +1,377 @@
+package fastmsgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// SelectExcept is the inverse of Select: it returns a copy of data without the requested fields.
+// Matching map entries are removed and matching array elements are left out, so "items[0]" shifts the other items down.
+// Everything else is copied as is, including length-prefixed entries (extension 17), flavors (extension 18) and void values that don't contain any of the fields.
+// Length-prefixed entries and flavors that do contain them are rebuilt; every case of a flavor is filtered, regardless of WithFlavorSelector.
+// Injections (extension 20) given with WithInjection are replaced by their filtered data if it contains any of the fields. Other injections are copied as is.
+// Filters of AddFilteredArrayResolver are ignored. Excluding the empty field "" replaces the whole value by nil.
+// An array element that fields address as both a map and an array (like "items[0].a" and "items[*][1]") is an error.
+// The result is appended to dst and returned. dst can be nil.
+func (r *Resolver) SelectExcept(dst, data []byte) ([]byte, error) {
+	var ex excluder
+	for _, o := range r.decodeOptions {
+		o(&ex.opt)
+	}
+	if err := ex.opt.CheckBytes(data); err != nil {
+		return nil, decodeErrorAt(err, 0, "")
+	}
+	out, _, _, err := ex.exclude(dst, data, r.interests)
+	if err != nil {
+		return nil, decodeErrorAt(err, 0, "")
+	}
+	return out, nil
+}
+
+type excluder struct {
+	opt internal.DecodeOptions
+}
+
+// excludedEntirely returns whether the interest covers the whole value, rather than some fields inside it.
+func excludedEntirely(interest any) bool {
+	switch interest.(type) {
+	case int, *nestedInterests:
+		return true
+	}
+	return false
+}
+
+// exclude appends the value at the start of data to dst without the fields in interest. It returns the number of bytes of data consumed and whether anything was left out.
+func (ex *excluder) exclude(dst, data []byte, interest any) (_ []byte, consumed int, changed bool, _ error) {
+	n, err := internal.ValueLength(data)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	value := data[:n]
+	if interest == nil {
+		return append(dst, value...), n, false, nil
+	}
+	if excludedEntirely(interest) {
+		return append(dst, 0xc0), n, true, nil
+	}
+	extType, payload, err := internal.DecodeExtensionHeader(value)
+	if err == nil {
+		dst, changed, err = ex.excludeFromExtension(dst, value, extType, payload, interest)
+		return dst, n, changed, err
+	} else if err != internal.ErrNotExtension {
+		return nil, 0, false, err
+	}
+	switch x := interest.(type) {
+	case map[string]any:
+		if elements, header, ok := internal.DecodeUnwrappedMapLen(value); ok {
+			dst, changed, err = ex.excludeFromMap(dst, value, elements, header, x)
+			return dst, n, changed, err
+		}
+	case *arrayInterests, subresolver:
+		if elements, header, ok := internal.DecodeUnwrappedArrayLen(value); ok {
+			dst, changed, err = ex.excludeFromArray(dst, value, elements, header, x)
+			return dst, n, changed, err
+		}
+	}
+	// The value doesn't have the shape of the interests, so it can't contain any of the fields.
+	return append(dst, value...), n, false, nil
+}
+
+// excludeFromExtension is exclude for extensions. The header of the extension is len(value)-len(payload) bytes long.
+func (ex *excluder) excludeFromExtension(dst, value []byte, extType int8, payload []byte, interest any) ([]byte, bool, error) {
+	header := len(value) - len(payload)
+	switch extType {
+	case 17: // Length-prefixed entry
+		if err := ex.opt.Descend(); err != nil {
+			return nil, false, err
+		}
+		defer ex.opt.Ascend()
+		start := len(dst)
+		var n int
+		var changed bool
+		var err error
+		dst, n, changed, err = ex.exclude(dst, payload, interest)
+		if err != nil {
+			return nil, false, decodeErrorAt(err, header, "")
+		}
+		if n != len(payload) {
+			return nil, false, fmt.Errorf("length-prefixed entry of %d bytes contains a value of %d bytes", len(payload), n)
+		}
+		if !changed {
+			return append(dst[:start], value...), false, nil
+		}
+		var h [6]byte
+		return slices.Insert(dst, start, appendLengthHeader(h[:0], len(dst)-start)...), true, nil
+
+	case 18: // Flavor pick
+		if err := ex.opt.Descend(); err != nil {
+			return nil, false, err
+		}
+		defer ex.opt.Ascend()
+		return ex.excludeFromFlavor(dst, value, header, payload, interest)
+
+	case 20: // Injection
+		if ex.opt.Injections == nil {
+			return append(dst, value...), false, nil
+		}
+		injected, err := internal.DecodeInjectionExtension(payload, ex.opt)
+		if err != nil {
+			return nil, false, err
+		}
+		start := len(dst)
+		var changed bool
+		dst, _, changed, err = ex.exclude(dst, injected, interest)
+		if err != nil {
+			return nil, false, injectedDecodeError(err)
+		}
+		if !changed {
+			return append(dst[:start], value...), false, nil
+		}
+		return dst, true, nil
+
+	default:
+		return append(dst, value...), false, nil
+	}
+}
+
+// excludeFromFlavor filters every case of a flavor and builds a new flavor from the results if any of them changed.
+func (ex *excluder) excludeFromFlavor(dst, value []byte, header int, payload []byte, interest any) ([]byte, bool, error) {
+	field, sz := binary.Uvarint(payload)
+	if sz <= 0 {
+		return nil, false, internal.ErrCorruptedFlavorData
+	}
+	fb := NewFlavorBuilder(uint(field))
+	var anyChanged bool
+	tableEnd, err := walkFlavorTable(payload, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = walkFlavorTable(payload, func(match, j uint64, isElse bool) error {
+		if j < uint64(tableEnd) || j >= uint64(len(payload)) {
+			return fmt.Errorf("flavor jump target %d is outside of the data (%d-%d)", j, tableEnd, len(payload))
+		}
+		filtered, _, changed, err := ex.exclude(nil, payload[j:], interest)
+		if err != nil {
+			return decodeErrorAt(err, header+int(j), "")
+		}
+		anyChanged = anyChanged || changed
+		if isElse {
+			fb.SetElse(filtered)
+		} else {
+			fb.AddCase(uint(match), filtered)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !anyChanged {
+		return append(dst, value...), false, nil
+	}
+	dst, err = fb.AppendMsgpack(dst)
+	return dst, true, err
+}
+
+// excludeFromMap is exclude for maps. The map has the given number of elements, which start header bytes into value.
+func (ex *excluder) excludeFromMap(dst, value []byte, elements, header int, interests map[string]any) ([]byte, bool, error) {
+	if err := ex.opt.CheckElements(elements, 2, len(value)-header); err != nil {
+		return nil, false, err
+	}
+	if err := ex.opt.Descend(); err != nil {
+		return nil, false, err
+	}
+	defer ex.opt.Ascend()
+	start := len(dst)
+	dst = append(dst, 0xdf, 0, 0, 0, 0)
+	var changed bool
+	newLength := 0
+	offset := header
+	for i := 0; elements > i; i++ {
+		keyLength, err := internal.ValueLength(value[offset:])
+		if err != nil {
+			return nil, false, decodeErrorAt(err, offset, "")
+		}
+		rawKey := value[offset : offset+keyLength]
+		kd := Decoder{root: rawKey, data: rawKey, opt: ex.opt}
+		k, ok, err := kd.decodeKeyString()
+		if err != nil && err != ErrVoid {
+			return nil, false, decodeErrorAt(err, offset, "")
+		}
+		var x any
+		if ok {
+			x = interests[k]
+		}
+		valueOffset := offset + keyLength
+		if excludedEntirely(x) {
+			n, err := internal.ValueLength(value[valueOffset:])
+			if err != nil {
+				return nil, false, decodeErrorAt(err, valueOffset, k)
+			}
+			offset = valueOffset + n
+			changed = true
+			continue
+		}
+		dst = append(dst, rawKey...)
+		var n int
+		var c bool
+		dst, n, c, err = ex.exclude(dst, value[valueOffset:], x)
+		if err != nil {
+			return nil, false, decodeErrorAt(err, valueOffset, keySegment(rawKey, ex.opt))
+		}
+		offset = valueOffset + n
+		changed = changed || c
+		newLength++
+	}
+	if !changed {
+		return append(dst[:start], value...), false, nil
+	}
+	binary.BigEndian.PutUint32(dst[start+1:], uint32(newLength))
+	return dst, true, nil
+}
+
+// excludeFromArray is exclude for arrays. The array has the given number of elements, which start header bytes into value.
+func (ex *excluder) excludeFromArray(dst, value []byte, elements, header int, interest any) ([]byte, bool, error) {
+	if err := ex.opt.CheckElements(elements, 1, len(value)-header); err != nil {
+		return nil, false, err
+	}
+	if err := ex.opt.Descend(); err != nil {
+		return nil, false, err
+	}
+	defer ex.opt.Ascend()
+	start := len(dst)
+	dst = append(dst, 0xdd, 0, 0, 0, 0)
+	var changed bool
+	newLength := 0
+	offset := header
+	for i := 0; elements > i; i++ {
+		var x any
+		switch interest := interest.(type) {
+		case *arrayInterests:
+			var err error
+			x, err = elementExclusion(interest, i, elements)
+			if err != nil {
+				return nil, false, decodeErrorAt(err, offset, internal.IndexSegment(i))
+			}
+		case subresolver:
+			x = interest.interests
+		}
+		if excludedEntirely(x) {
+			n, err := internal.ValueLength(value[offset:])
+			if err != nil {
+				return nil, false, decodeErrorAt(err, offset, internal.IndexSegment(i))
+			}
+			offset += n
+			changed = true
+			continue
+		}
+		var n int
+		var c bool
+		var err error
+		dst, n, c, err = ex.exclude(dst, value[offset:], x)
+		if err != nil {
+			return nil, false, decodeErrorAt(err, offset, internal.IndexSegment(i))
+		}
+		offset += n
+		changed = changed || c
+		newLength++
+	}
+	if !changed {
+		return append(dst[:start], value...), false, nil
+	}
+	binary.BigEndian.PutUint32(dst[start+1:], uint32(newLength))
+	return dst, true, nil
+}
+
+var errConflictingShapes = errors.New("conflicting fields: can't exclude from an element that is both a map and an array")
+
+// elementExclusion is like elementInterest, but merges the interests in a way that is safe for excluding.
+// mergeInterests falls back to taking the whole value, which would leave out the entire element.
+func elementExclusion(ai *arrayInterests, i, elements int) (any, error) {
+	var ret any
+	if ai.wildcard != nil {
+		ret = ai.wildcard.interests
+	}
+	var err error
+	if x, ok := ai.indexes[i]; ok {
+		if ret, err = mergeExclusions(ret, x); err != nil {
+			return nil, err
+		}
+	}
+	if x, ok := ai.indexes[i-elements]; ok {
+		if ret, err = mergeExclusions(ret, x); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// mergeExclusions combines what we want to leave out of a value. It returns errConflictingShapes if one of them expects a map and the other an array.
+func mergeExclusions(a, b any) (any, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	if excludedEntirely(a) || excludedEntirely(b) {
+		return 0, nil
+	}
+	a, b = exclusionAsArray(a), exclusionAsArray(b)
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			ret := make(map[string]any, len(a)+len(b))
+			for k, v := range a {
+				ret[k] = v
+			}
+			for k, v := range b {
+				m, err := mergeExclusions(ret[k], v)
+				if err != nil {
+					return nil, err
+				}
+				ret[k] = m
+			}
+			return ret, nil
+		}
+	case *arrayInterests:
+		if b, ok := b.(*arrayInterests); ok {
+			ret := &arrayInterests{indexes: make(map[int]any, len(a.indexes)+len(b.indexes))}
+			for k, v := range a.indexes {
+				ret.indexes[k] = v
+			}
+			for k, v := range b.indexes {
+				m, err := mergeExclusions(ret.indexes[k], v)
+				if err != nil {
+					return nil, err
+				}
+				ret.indexes[k] = m
+			}
+			switch {
+			case a.wildcard == nil:
+				ret.wildcard = b.wildcard
+			case b.wildcard == nil:
+				ret.wildcard = a.wildcard
+			default:
+				m, err := mergeExclusions(a.wildcard.interests, b.wildcard.interests)
+				if err != nil {
+					return nil, err
+				}
+				ret.wildcard = &wildcardInterests{interests: m}
+			}
+			return ret, nil
+		}
+	}
+	return nil, errConflictingShapes
+}
+
+// exclusionAsArray returns a subresolver as the equivalent *arrayInterests, because SelectExcept excludes its fields from every element.
+func exclusionAsArray(interest any) any {
+	if sub, ok := interest.(subresolver); ok {
+		return &arrayInterests{wildcard: &wildcardInterests{interests: sub.interests}}
+	}
+	return interest
+}
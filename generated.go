@@ -76,7 +76,7 @@ func decodeValue(data []byte, opt internal.DecodeOptions) (any, int, error) {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[3:s], int8(data[2]), opt)
-		return ret, s, err
+		return ret, s, shiftDecodeError(err, 3)
 	case 0xc8:
 		if len(data) < 4 {
 			return nil, 0, internal.ErrShortInput
@@ -86,7 +86,7 @@ func decodeValue(data []byte, opt internal.DecodeOptions) (any, int, error) {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[4:s], int8(data[3]), opt)
-		return ret, s, err
+		return ret, s, shiftDecodeError(err, 4)
 	case 0xc9:
 		if len(data) < 6 {
 			return nil, 0, internal.ErrShortInput
@@ -96,7 +96,7 @@ func decodeValue(data []byte, opt internal.DecodeOptions) (any, int, error) {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[6:s], int8(data[5]), opt)
-		return ret, s, err
+		return ret, s, shiftDecodeError(err, 6)
 	case 0xca:
 		if len(data) < 5 {
 			return nil, 0, internal.ErrShortInput
@@ -126,7 +126,16 @@ func decodeValue(data []byte, opt internal.DecodeOptions) (any, int, error) {
 		if len(data) < 9 {
 			return nil, 0, internal.ErrShortInput
 		}
-		return int(binary.BigEndian.Uint64(data[1:9])), 9, nil
+		n := binary.BigEndian.Uint64(data[1:9])
+		if n > math.MaxInt {
+			if opt.Uint64 {
+				return n, 9, nil
+			}
+			if opt.IntOverflowError {
+				return nil, 0, internal.ErrIntOverflow
+			}
+		}
+		return int(n), 9, nil
 	case 0xd0:
 		if len(data) < 2 {
 			return nil, 0, internal.ErrShortInput
@@ -152,31 +161,31 @@ func decodeValue(data []byte, opt internal.DecodeOptions) (any, int, error) {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[2:3], int8(data[1]), opt)
-		return ret, 3, err
+		return ret, 3, shiftDecodeError(err, 2)
 	case 0xd5:
 		if len(data) < 4 {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[2:4], int8(data[1]), opt)
-		return ret, 4, err
+		return ret, 4, shiftDecodeError(err, 2)
 	case 0xd6:
 		if len(data) < 6 {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[2:6], int8(data[1]), opt)
-		return ret, 6, err
+		return ret, 6, shiftDecodeError(err, 2)
 	case 0xd7:
 		if len(data) < 10 {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[2:10], int8(data[1]), opt)
-		return ret, 10, err
+		return ret, 10, shiftDecodeError(err, 2)
 	case 0xd8:
 		if len(data) < 18 {
 			return nil, 0, internal.ErrShortInput
 		}
 		ret, err := decodeValue_ext(data[2:18], int8(data[1]), opt)
-		return ret, 18, err
+		return ret, 18, shiftDecodeError(err, 2)
 	case 0xd9:
 		if len(data) < 2 {
 			return nil, 0, internal.ErrShortInput
@@ -350,6 +359,75 @@ func DecodeType(data []byte) ValueType {
 	return TypeInvalid
 }
 
+func decodeExtension(data []byte, opt internal.DecodeOptions) (Extension, int, error) {
+	if len(data) < 1 {
+		return Extension{}, 0, internal.ErrShortInput
+	}
+	switch data[0] {
+	case 0xc7:
+		if len(data) < 3 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		s := int(data[1]) + 3
+		if len(data) < s {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[3:s], int8(data[2]), opt)
+		return ret, s, err
+	case 0xc8:
+		if len(data) < 4 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 4
+		if len(data) < s {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[4:s], int8(data[3]), opt)
+		return ret, s, err
+	case 0xc9:
+		if len(data) < 6 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 6
+		if len(data) < s {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[6:s], int8(data[5]), opt)
+		return ret, s, err
+	case 0xd4:
+		if len(data) < 3 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[2:3], int8(data[1]), opt)
+		return ret, 3, err
+	case 0xd5:
+		if len(data) < 4 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[2:4], int8(data[1]), opt)
+		return ret, 4, err
+	case 0xd6:
+		if len(data) < 6 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[2:6], int8(data[1]), opt)
+		return ret, 6, err
+	case 0xd7:
+		if len(data) < 10 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[2:10], int8(data[1]), opt)
+		return ret, 10, err
+	case 0xd8:
+		if len(data) < 18 {
+			return Extension{}, 0, internal.ErrShortInput
+		}
+		ret, err := decodeExtension_ext(data[2:18], int8(data[1]), opt)
+		return ret, 18, err
+	}
+	return Extension{}, 0, errors.New("unexpected " + internal.DescribeValue(data) + " when expecting Extension")
+}
+
 func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 	if len(data) < 1 {
 		return 0, internal.ErrShortInput
@@ -415,7 +493,7 @@ func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 		if len(data) < s {
 			return 0, internal.ErrShortInput
 		}
-		return s, c.canonicalize_ext(data[3:s], int8(data[2]))
+		return s, shiftDecodeError(c.canonicalize_ext(data[3:s], int8(data[2])), 3)
 	case 0xc8:
 		if len(data) < 4 {
 			return 0, internal.ErrShortInput
@@ -424,7 +502,7 @@ func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 		if len(data) < s {
 			return 0, internal.ErrShortInput
 		}
-		return s, c.canonicalize_ext(data[4:s], int8(data[3]))
+		return s, shiftDecodeError(c.canonicalize_ext(data[4:s], int8(data[3])), 4)
 	case 0xc9:
 		if len(data) < 6 {
 			return 0, internal.ErrShortInput
@@ -433,7 +511,7 @@ func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 		if len(data) < s {
 			return 0, internal.ErrShortInput
 		}
-		return s, c.canonicalize_ext(data[6:s], int8(data[5]))
+		return s, shiftDecodeError(c.canonicalize_ext(data[6:s], int8(data[5])), 6)
 	case 0xca:
 		if len(data) < 5 {
 			return 0, internal.ErrShortInput
@@ -488,27 +566,27 @@ func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 		if len(data) < 3 {
 			return 0, internal.ErrShortInput
 		}
-		return 3, c.canonicalize_ext(data[2:3], int8(data[1]))
+		return 3, shiftDecodeError(c.canonicalize_ext(data[2:3], int8(data[1])), 2)
 	case 0xd5:
 		if len(data) < 4 {
 			return 0, internal.ErrShortInput
 		}
-		return 4, c.canonicalize_ext(data[2:4], int8(data[1]))
+		return 4, shiftDecodeError(c.canonicalize_ext(data[2:4], int8(data[1])), 2)
 	case 0xd6:
 		if len(data) < 6 {
 			return 0, internal.ErrShortInput
 		}
-		return 6, c.canonicalize_ext(data[2:6], int8(data[1]))
+		return 6, shiftDecodeError(c.canonicalize_ext(data[2:6], int8(data[1])), 2)
 	case 0xd7:
 		if len(data) < 10 {
 			return 0, internal.ErrShortInput
 		}
-		return 10, c.canonicalize_ext(data[2:10], int8(data[1]))
+		return 10, shiftDecodeError(c.canonicalize_ext(data[2:10], int8(data[1])), 2)
 	case 0xd8:
 		if len(data) < 18 {
 			return 0, internal.ErrShortInput
 		}
-		return 18, c.canonicalize_ext(data[2:18], int8(data[1]))
+		return 18, shiftDecodeError(c.canonicalize_ext(data[2:18], int8(data[1])), 2)
 	case 0xd9:
 		if len(data) < 2 {
 			return 0, internal.ErrShortInput
@@ -559,3 +637,213 @@ func (c *canonicalizer) canonicalize(data []byte) (int, error) {
 	}
 	return 0, errors.New("unexpected 0xc1")
 }
+
+func (v *validator) validateValue(data []byte) (int, error) {
+	if len(data) < 1 {
+		return 0, internal.ErrShortInput
+	}
+	if data[0] < 0xc0 {
+		if data[0] <= 0x7f {
+			return 1, nil
+		}
+		if data[0] <= 0x8f {
+			return v.validateValue_map(data, 1, int(data[0]&0b00001111))
+		}
+		if data[0] <= 0x9f {
+			return v.validateValue_array(data, 1, int(data[0]&0b00001111))
+		}
+		s := int(data[0]&0b00011111) + 1
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateString(internal.UnsafeStringCast(data[1:s]))
+	}
+	if data[0] >= 0xe0 {
+		return 1, nil
+	}
+	switch data[0] {
+	case 0xc0:
+		return 1, nil
+	case 0xc2:
+		return 1, nil
+	case 0xc3:
+		return 1, nil
+	case 0xc4:
+		if len(data) < 2 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(data[1]) + 2
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, nil
+	case 0xc5:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 3
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, nil
+	case 0xc6:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 5
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, nil
+	case 0xc7:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(data[1]) + 3
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateValue_ext(3, data[3:s], int8(data[2]))
+	case 0xc8:
+		if len(data) < 4 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 4
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateValue_ext(4, data[4:s], int8(data[3]))
+	case 0xc9:
+		if len(data) < 6 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 6
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateValue_ext(6, data[6:s], int8(data[5]))
+	case 0xca:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		return 5, nil
+	case 0xcb:
+		if len(data) < 9 {
+			return 0, internal.ErrShortInput
+		}
+		return 9, nil
+	case 0xcc:
+		if len(data) < 2 {
+			return 0, internal.ErrShortInput
+		}
+		return 2, nil
+	case 0xcd:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		return 3, nil
+	case 0xce:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		return 5, nil
+	case 0xcf:
+		if len(data) < 9 {
+			return 0, internal.ErrShortInput
+		}
+		return 9, nil
+	case 0xd0:
+		if len(data) < 2 {
+			return 0, internal.ErrShortInput
+		}
+		return 2, nil
+	case 0xd1:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		return 3, nil
+	case 0xd2:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		return 5, nil
+	case 0xd3:
+		if len(data) < 9 {
+			return 0, internal.ErrShortInput
+		}
+		return 9, nil
+	case 0xd4:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		return 3, v.validateValue_ext(2, data[2:3], int8(data[1]))
+	case 0xd5:
+		if len(data) < 4 {
+			return 0, internal.ErrShortInput
+		}
+		return 4, v.validateValue_ext(2, data[2:4], int8(data[1]))
+	case 0xd6:
+		if len(data) < 6 {
+			return 0, internal.ErrShortInput
+		}
+		return 6, v.validateValue_ext(2, data[2:6], int8(data[1]))
+	case 0xd7:
+		if len(data) < 10 {
+			return 0, internal.ErrShortInput
+		}
+		return 10, v.validateValue_ext(2, data[2:10], int8(data[1]))
+	case 0xd8:
+		if len(data) < 18 {
+			return 0, internal.ErrShortInput
+		}
+		return 18, v.validateValue_ext(2, data[2:18], int8(data[1]))
+	case 0xd9:
+		if len(data) < 2 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(data[1]) + 2
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateString(internal.UnsafeStringCast(data[2:s]))
+	case 0xda:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 3
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateString(internal.UnsafeStringCast(data[3:s]))
+	case 0xdb:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 5
+		if len(data) < s {
+			return 0, internal.ErrShortInput
+		}
+		return s, v.validateString(internal.UnsafeStringCast(data[5:s]))
+	case 0xdc:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		return v.validateValue_array(data, 3, int(binary.BigEndian.Uint16(data[1:3])))
+	case 0xdd:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		return v.validateValue_array(data, 5, int(binary.BigEndian.Uint32(data[1:5])))
+	case 0xde:
+		if len(data) < 3 {
+			return 0, internal.ErrShortInput
+		}
+		return v.validateValue_map(data, 3, int(binary.BigEndian.Uint16(data[1:3])))
+	case 0xdf:
+		if len(data) < 5 {
+			return 0, internal.ErrShortInput
+		}
+		return v.validateValue_map(data, 5, int(binary.BigEndian.Uint32(data[1:5])))
+	}
+	return 0, errors.New("unexpected 0xc1")
+}
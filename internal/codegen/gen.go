@@ -39,9 +39,12 @@ func main() {
 		generate(&buf, "_desc", "DescribeValue")
 		generate(&buf, "string", "DecodeString")
 		generate(&buf, "int", "DecodeInt")
+		generate(&buf, "uint64", "DecodeUint")
 		generate(&buf, "float32", "DecodeFloat32")
 		generate(&buf, "float64", "DecodeFloat64")
 		generate(&buf, "bool", "DecodeBool")
+		generate(&buf, "[]byte", "DecodeBytes")
+		generate(&buf, "nil", "DecodeNil")
 		generate(&buf, "time", "DecodeTime")
 		generate(&buf, "map", "DecodeMapLen")
 		generate(&buf, "array", "DecodeArrayLen")
@@ -71,7 +74,9 @@ func main() {
 		fmt.Fprintf(&buf, ")\n")
 		generate(&buf, "any", "decodeValue")
 		generate(&buf, "type", "DecodeType")
+		generate(&buf, "Extension", "decodeExtension")
 		generate(&buf, "canonical", "canonicalize")
+		generate(&buf, "validate", "validateValue")
 
 		formatted, err := format.Source(buf.Bytes())
 		if err != nil {
@@ -143,6 +148,8 @@ func generate(w *bytes.Buffer, retType, name string) {
 		fmt.Fprintf(w, "func (p *printer) %s(data []byte) (int, error) {\n", name)
 	case "canonical":
 		fmt.Fprintf(w, "func (c *canonicalizer) %s(data []byte) (int, error) {\n", name)
+	case "validate":
+		fmt.Fprintf(w, "func (v *validator) %s(data []byte) (int, error) {\n", name)
 	case "any":
 		fmt.Fprintf(w, "func %s(data []byte, opt internal.DecodeOptions) (any, int, error) {\n", name)
 	case "_desc":
@@ -155,6 +162,9 @@ func generate(w *bytes.Buffer, retType, name string) {
 	case "time":
 		fmt.Fprintf(w, "func %s(data []byte, opt internal.DecodeOptions) (time.Time, int, error) {\n", name)
 		typeRestricted = true
+	case "nil":
+		fmt.Fprintf(w, "func %s(data []byte, opt internal.DecodeOptions) (struct{}, int, error) {\n", name)
+		typeRestricted = true
 	default:
 		fmt.Fprintf(w, "func %s(data []byte, opt internal.DecodeOptions) (%s, int, error) {\n", name, retType)
 		typeRestricted = true
@@ -167,11 +177,11 @@ func generate(w *bytes.Buffer, retType, name string) {
 	default:
 		emitLengthCheck(w, retType, MsgpackType{}, fmt.Sprint(guaranteedLength), "internal.ErrShortInput")
 	}
-	if retType == "string" {
+	if retType == "string" || retType == "[]byte" {
 		fmt.Fprintf(w, "	if data[0] & 0b11100000 == 0b10100000 {\n")
 		generateDecodeType(w, retType, name, guaranteedLength, fixstr)
 		fmt.Fprintf(w, "	}\n")
-	} else if retType == "int" || retType == "float32" || retType == "float64" {
+	} else if isNumericType(retType) {
 		fmt.Fprintf(w, "	if data[0] <= 0x7f {\n")
 		generateDecodeType(w, retType, name, guaranteedLength, positiveFixint)
 		fmt.Fprintf(w, "	}\n")
@@ -197,15 +207,15 @@ func generate(w *bytes.Buffer, retType, name string) {
 		generateDecodeType(w, retType, name, guaranteedLength, fixstr)
 		fmt.Fprintf(w, "	}\n")
 	}
-	if !typeRestricted || retType == "int" || retType == "float32" || retType == "float64" {
+	if !typeRestricted || isNumericType(retType) {
 		fmt.Fprintf(w, "	if data[0] >= 0xe0 {\n")
 		generateDecodeType(w, retType, name, guaranteedLength, negativeFixint)
 		fmt.Fprintf(w, "	}\n")
 	}
 	fmt.Fprintf(w, "	switch data[0] {\n")
-	if typeRestricted && retType != "time" {
+	if typeRestricted && retType != "time" && retType != "Extension" {
 		for _, t := range types {
-			if t.ByteEnd != 0 || (t.DataType != retType && !(isNumericType(t.DataType) && isNumericType(retType)) && !(retType == "string" && t.DataType == "[]byte")) {
+			if t.ByteEnd != 0 || (t.DataType != retType && !(isNumericType(t.DataType) && isNumericType(retType)) && !(retType == "string" && t.DataType == "[]byte") && !(retType == "[]byte" && t.DataType == "string")) {
 				continue
 			}
 			fmt.Fprintf(w, "	case 0x%02x:\n", t.Byte)
@@ -230,7 +240,7 @@ func generate(w *bytes.Buffer, retType, name string) {
 	}
 	fmt.Fprintf(w, "	}\n")
 	switch retType {
-	case "skip", "json", "debug", "canonical":
+	case "skip", "json", "debug", "canonical", "validate":
 		fmt.Fprintf(w, "	return 0, errors.New(%q)\n", "unexpected 0xc1")
 	case "_desc":
 		fmt.Fprintf(w, "	return %q\n", "0xc1")
@@ -258,6 +268,8 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 		}
 	}
 	minLen, preamble, val, lencalc := getDecoder(t)
+	// extHeader is where the extension data starts.
+	extHeader := genericz.Ternary(strings.HasPrefix(t.Name, "fixext"), t.DataStart, minLen)
 	if minLen > guaranteedLength {
 		emitLengthCheck(w, retType, t, fmt.Sprint(minLen), "internal.ErrShortInput")
 	}
@@ -311,7 +323,7 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 		case "map":
 			fmt.Fprintf(w, "		return p.%s_map(data, %s, %s)\n", lcfirst(thisFunc), lencalc, val)
 		case "ext":
-			fmt.Fprintf(w, "		return %s, p.%s_ext(data[:%d], %s, int8(data[%d]))\n", lencalc, lcfirst(thisFunc), genericz.Ternary(strings.HasPrefix(t.Name, "fixext"), t.DataStart, minLen), val, t.ExtTypeAt)
+			fmt.Fprintf(w, "		return %s, p.%s_ext(data[:%d], %s, int8(data[%d]))\n", lencalc, lcfirst(thisFunc), extHeader, val, t.ExtTypeAt)
 		case "[]byte":
 			fmt.Fprintf(w, "		return %s, p.appendBytes(data[:%d], %s)\n", lencalc, minLen, val)
 		default:
@@ -325,7 +337,7 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 		case "map":
 			fmt.Fprintf(w, "		return c.%s_map(data, %s, %s)\n", lcfirst(thisFunc), lencalc, val)
 		case "ext":
-			fmt.Fprintf(w, "		return %s, c.%s_ext(%s, int8(data[%d]))\n", lencalc, lcfirst(thisFunc), val, t.ExtTypeAt)
+			fmt.Fprintf(w, "		return %s, shiftDecodeError(c.%s_ext(%s, int8(data[%d])), %d)\n", lencalc, lcfirst(thisFunc), val, t.ExtTypeAt, extHeader)
 		case "[]byte":
 			fmt.Fprintf(w, "		return %s, c.appendBytes(%s)\n", lencalc, val)
 		case "nil", "bool", "float32", "float64":
@@ -336,6 +348,20 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 			fmt.Fprintf(w, "		return %s, c.append%s(%s)\n", lencalc, ucfirst(t.DataType), val)
 		}
 		return
+	case "validate":
+		switch t.DataType {
+		case "array":
+			fmt.Fprintf(w, "		return v.%s_array(data, %s, %s)\n", lcfirst(thisFunc), lencalc, val)
+		case "map":
+			fmt.Fprintf(w, "		return v.%s_map(data, %s, %s)\n", lcfirst(thisFunc), lencalc, val)
+		case "ext":
+			fmt.Fprintf(w, "		return %s, v.%s_ext(%d, %s, int8(data[%d]))\n", lencalc, lcfirst(thisFunc), extHeader, val, t.ExtTypeAt)
+		case "string":
+			fmt.Fprintf(w, "		return %s, v.validateString(%s)\n", lencalc, val)
+		default:
+			fmt.Fprintf(w, "		return %s, nil\n", lencalc)
+		}
+		return
 	case "skip":
 		switch t.DataType {
 		case "array":
@@ -365,6 +391,21 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 		}
 		return
 	}
+	if t.Byte == 0xcf && (retType == "int" || retType == "any") {
+		// uint 64 doesn't always fit in an int.
+		fmt.Fprintf(w, "		n := binary.BigEndian.Uint64(data[1:9])\n")
+		fmt.Fprintf(w, "		if n > math.MaxInt {\n")
+		if retType == "any" {
+			fmt.Fprintf(w, "			if opt.Uint64 {\n")
+			fmt.Fprintf(w, "				return n, 9, nil\n")
+			fmt.Fprintf(w, "			}\n")
+		}
+		fmt.Fprintf(w, "			if opt.IntOverflowError {\n")
+		fmt.Fprintf(w, "				return %s, 0, internal.ErrIntOverflow\n", produceZero(retType))
+		fmt.Fprintf(w, "			}\n")
+		fmt.Fprintf(w, "		}\n")
+		val = "int(n)"
+	}
 	switch t.DataType {
 	case "array":
 		switch retType {
@@ -382,8 +423,20 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 		}
 	case "ext":
 		fmt.Fprintf(w, "		ret, err := %s_ext(%s, int8(data[%d]), opt)\n", lcfirst(thisFunc), val, t.ExtTypeAt)
-		fmt.Fprintf(w, "		return ret, %s, err\n", lencalc)
+		if retType == "any" {
+			// Errors from within the extension data are relative to where that data starts.
+			fmt.Fprintf(w, "		return ret, %s, shiftDecodeError(err, %d)\n", lencalc, extHeader)
+		} else {
+			fmt.Fprintf(w, "		return ret, %s, err\n", lencalc)
+		}
 	default:
+		if retType == "uint64" && isNumericType(t.DataType) && !isUnsigned(t) {
+			fmt.Fprintf(w, "		if v := %s; v >= 0 {\n", val)
+			fmt.Fprintf(w, "			return uint64(v), %s, nil\n", lencalc)
+			fmt.Fprintf(w, "		}\n")
+			fmt.Fprintf(w, "		return 0, 0, errors.New(%q)\n", "negative number in "+t.Name+" when expecting uint")
+			break
+		}
 		if retType != t.DataType && isNumericType(retType) && isNumericType(t.DataType) {
 			fmt.Fprintf(w, "		return %s(%s), %s, nil\n", retType, val, lencalc)
 			break
@@ -392,6 +445,14 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 			fmt.Fprintf(w, "		return internal.UnsafeStringCast(%s), %s, nil\n", val, lencalc)
 			break
 		}
+		if retType == "[]byte" && t.DataType == "string" {
+			fmt.Fprintf(w, "		return %s, %s, nil\n", regexp.MustCompile(`^internal\.UnsafeStringCast\((.+)\)$`).ReplaceAllString(val, "$1"), lencalc)
+			break
+		}
+		if retType == "nil" && t.DataType == "nil" {
+			fmt.Fprintf(w, "		return struct{}{}, %s, nil\n", lencalc)
+			break
+		}
 		switch retType {
 		case "any", t.DataType:
 			fmt.Fprintf(w, "		return %s, %s, nil\n", val, lencalc)
@@ -404,7 +465,7 @@ func generateDecodeType(w *bytes.Buffer, retType, thisFunc string, guaranteedLen
 func emitLengthCheck(w *bytes.Buffer, retType string, t MsgpackType, minLen string, errName string) {
 	fmt.Fprintf(w, "		if len(data) < %s {\n", minLen)
 	switch retType {
-	case "skip", "json", "debug", "canonical":
+	case "skip", "json", "debug", "canonical", "validate":
 		fmt.Fprintf(w, "			return 0, %s\n", errName)
 	case "_desc":
 		if minLen == "1" {
@@ -456,10 +517,14 @@ func produceZero(retType string) string {
 	switch retType {
 	case "string":
 		return `""`
-	case "int", "float32", "float64":
+	case "int", "uint64", "float32", "float64":
 		return "0"
 	case "time":
 		return "time.Time{}"
+	case "nil":
+		return "struct{}{}"
+	case "Extension":
+		return "Extension{}"
 	case "bool":
 		return "false"
 	default:
@@ -477,9 +542,14 @@ func ucfirst(s string) string {
 
 func isNumericType(t string) bool {
 	switch t {
-	case "int", "float32", "float64":
+	case "int", "uint64", "float32", "float64":
 		return true
 	default:
 		return false
 	}
 }
+
+// isUnsigned returns whether t can never hold a negative number.
+func isUnsigned(t MsgpackType) bool {
+	return t.Name == "positive fixint" || strings.HasPrefix(t.Name, "uint ")
+}
@@ -10,6 +10,8 @@ type Dict struct {
 	Strings     []string
 	Interfaces  []any
 	JSONEncoded *atomic.Pointer[[][]byte]
+	// Origin is the *fastmsgpack.Dict this was created from, to recognize which dict is in use.
+	Origin any
 }
 
 func (d *Dict) LookupAny(n uint) (any, error) {
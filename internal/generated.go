@@ -604,7 +604,13 @@ func DecodeInt(data []byte, opt DecodeOptions) (int, int, error) {
 		if len(data) < 9 {
 			return 0, 0, ErrShortInput
 		}
-		return int(binary.BigEndian.Uint64(data[1:9])), 9, nil
+		n := binary.BigEndian.Uint64(data[1:9])
+		if n > math.MaxInt {
+			if opt.IntOverflowError {
+				return 0, 0, ErrIntOverflow
+			}
+		}
+		return int(n), 9, nil
 	case 0xd0:
 		if len(data) < 2 {
 			return 0, 0, ErrShortInput
@@ -693,6 +699,156 @@ func DecodeInt(data []byte, opt DecodeOptions) (int, int, error) {
 	return 0, 0, errors.New("unexpected " + DescribeValue(data) + " when expecting int")
 }
 
+func DecodeUint(data []byte, opt DecodeOptions) (uint64, int, error) {
+	if len(data) < 1 {
+		return 0, 0, ErrShortInput
+	}
+	if data[0] <= 0x7f {
+		return uint64(int(data[0])), 1, nil
+	}
+	if data[0] >= 0xe0 {
+		if v := int(int8(data[0])); v >= 0 {
+			return uint64(v), 1, nil
+		}
+		return 0, 0, errors.New("negative number in negative fixint when expecting uint")
+	}
+	switch data[0] {
+	case 0xca:
+		if len(data) < 5 {
+			return 0, 0, ErrShortInput
+		}
+		if v := math.Float32frombits(binary.BigEndian.Uint32(data[1:5])); v >= 0 {
+			return uint64(v), 5, nil
+		}
+		return 0, 0, errors.New("negative number in float 32 when expecting uint")
+	case 0xcb:
+		if len(data) < 9 {
+			return 0, 0, ErrShortInput
+		}
+		if v := math.Float64frombits(binary.BigEndian.Uint64(data[1:9])); v >= 0 {
+			return uint64(v), 9, nil
+		}
+		return 0, 0, errors.New("negative number in float 64 when expecting uint")
+	case 0xcc:
+		if len(data) < 2 {
+			return 0, 0, ErrShortInput
+		}
+		return uint64(int(data[1])), 2, nil
+	case 0xcd:
+		if len(data) < 3 {
+			return 0, 0, ErrShortInput
+		}
+		return uint64(int(binary.BigEndian.Uint16(data[1:3]))), 3, nil
+	case 0xce:
+		if len(data) < 5 {
+			return 0, 0, ErrShortInput
+		}
+		return uint64(int(binary.BigEndian.Uint32(data[1:5]))), 5, nil
+	case 0xcf:
+		if len(data) < 9 {
+			return 0, 0, ErrShortInput
+		}
+		return uint64(int(binary.BigEndian.Uint64(data[1:9]))), 9, nil
+	case 0xd0:
+		if len(data) < 2 {
+			return 0, 0, ErrShortInput
+		}
+		if v := int(int8(data[1])); v >= 0 {
+			return uint64(v), 2, nil
+		}
+		return 0, 0, errors.New("negative number in int 8 when expecting uint")
+	case 0xd1:
+		if len(data) < 3 {
+			return 0, 0, ErrShortInput
+		}
+		if v := int(int16(binary.BigEndian.Uint16(data[1:3]))); v >= 0 {
+			return uint64(v), 3, nil
+		}
+		return 0, 0, errors.New("negative number in int 16 when expecting uint")
+	case 0xd2:
+		if len(data) < 5 {
+			return 0, 0, ErrShortInput
+		}
+		if v := int(int32(binary.BigEndian.Uint32(data[1:5]))); v >= 0 {
+			return uint64(v), 5, nil
+		}
+		return 0, 0, errors.New("negative number in int 32 when expecting uint")
+	case 0xd3:
+		if len(data) < 9 {
+			return 0, 0, ErrShortInput
+		}
+		if v := int(int64(binary.BigEndian.Uint64(data[1:9]))); v >= 0 {
+			return uint64(v), 9, nil
+		}
+		return 0, 0, errors.New("negative number in int 64 when expecting uint")
+	}
+
+	// Try extension decoding in case of a length-prefixed entry (#17) or flavors (#18)
+	switch data[0] {
+	case 0xc7:
+		if len(data) < 3 {
+			return 0, 0, ErrShortInput
+		}
+		s := int(data[1]) + 3
+		if len(data) < s {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[3:s], int8(data[2]), opt)
+		return ret, s, err
+	case 0xc8:
+		if len(data) < 4 {
+			return 0, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 4
+		if len(data) < s {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[4:s], int8(data[3]), opt)
+		return ret, s, err
+	case 0xc9:
+		if len(data) < 6 {
+			return 0, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 6
+		if len(data) < s {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[6:s], int8(data[5]), opt)
+		return ret, s, err
+	case 0xd4:
+		if len(data) < 3 {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[2:3], int8(data[1]), opt)
+		return ret, 3, err
+	case 0xd5:
+		if len(data) < 4 {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[2:4], int8(data[1]), opt)
+		return ret, 4, err
+	case 0xd6:
+		if len(data) < 6 {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[2:6], int8(data[1]), opt)
+		return ret, 6, err
+	case 0xd7:
+		if len(data) < 10 {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[2:10], int8(data[1]), opt)
+		return ret, 10, err
+	case 0xd8:
+		if len(data) < 18 {
+			return 0, 0, ErrShortInput
+		}
+		ret, err := decodeUint_ext(data[2:18], int8(data[1]), opt)
+		return ret, 18, err
+	}
+	return 0, 0, errors.New("unexpected " + DescribeValue(data) + " when expecting uint64")
+}
+
 func DecodeFloat32(data []byte, opt DecodeOptions) (float32, int, error) {
 	if len(data) < 1 {
 		return 0, 0, ErrShortInput
@@ -1028,6 +1184,215 @@ func DecodeBool(data []byte, opt DecodeOptions) (bool, int, error) {
 	return false, 0, errors.New("unexpected " + DescribeValue(data) + " when expecting bool")
 }
 
+func DecodeBytes(data []byte, opt DecodeOptions) ([]byte, int, error) {
+	if len(data) < 1 {
+		return nil, 0, ErrShortInput
+	}
+	if data[0]&0b11100000 == 0b10100000 {
+		s := int(data[0]&0b00011111) + 1
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[1:s], s, nil
+	}
+	switch data[0] {
+	case 0xc4:
+		if len(data) < 2 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(data[1]) + 2
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[2:s], s, nil
+	case 0xc5:
+		if len(data) < 3 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 3
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[3:s], s, nil
+	case 0xc6:
+		if len(data) < 5 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 5
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[5:s], s, nil
+	case 0xd9:
+		if len(data) < 2 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(data[1]) + 2
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[2:s], s, nil
+	case 0xda:
+		if len(data) < 3 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 3
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[3:s], s, nil
+	case 0xdb:
+		if len(data) < 5 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 5
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		return data[5:s], s, nil
+	}
+
+	// Try extension decoding in case of a length-prefixed entry (#17) or flavors (#18)
+	switch data[0] {
+	case 0xc7:
+		if len(data) < 3 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(data[1]) + 3
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[3:s], int8(data[2]), opt)
+		return ret, s, err
+	case 0xc8:
+		if len(data) < 4 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 4
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[4:s], int8(data[3]), opt)
+		return ret, s, err
+	case 0xc9:
+		if len(data) < 6 {
+			return nil, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 6
+		if len(data) < s {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[6:s], int8(data[5]), opt)
+		return ret, s, err
+	case 0xd4:
+		if len(data) < 3 {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[2:3], int8(data[1]), opt)
+		return ret, 3, err
+	case 0xd5:
+		if len(data) < 4 {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[2:4], int8(data[1]), opt)
+		return ret, 4, err
+	case 0xd6:
+		if len(data) < 6 {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[2:6], int8(data[1]), opt)
+		return ret, 6, err
+	case 0xd7:
+		if len(data) < 10 {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[2:10], int8(data[1]), opt)
+		return ret, 10, err
+	case 0xd8:
+		if len(data) < 18 {
+			return nil, 0, ErrShortInput
+		}
+		ret, err := decodeBytes_ext(data[2:18], int8(data[1]), opt)
+		return ret, 18, err
+	}
+	return nil, 0, errors.New("unexpected " + DescribeValue(data) + " when expecting []byte")
+}
+
+func DecodeNil(data []byte, opt DecodeOptions) (struct{}, int, error) {
+	if len(data) < 1 {
+		return struct{}{}, 0, ErrShortInput
+	}
+	switch data[0] {
+	case 0xc0:
+		return struct{}{}, 1, nil
+	}
+
+	// Try extension decoding in case of a length-prefixed entry (#17) or flavors (#18)
+	switch data[0] {
+	case 0xc7:
+		if len(data) < 3 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		s := int(data[1]) + 3
+		if len(data) < s {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[3:s], int8(data[2]), opt)
+		return ret, s, err
+	case 0xc8:
+		if len(data) < 4 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint16(data[1:3])) + 4
+		if len(data) < s {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[4:s], int8(data[3]), opt)
+		return ret, s, err
+	case 0xc9:
+		if len(data) < 6 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		s := int(binary.BigEndian.Uint32(data[1:5])) + 6
+		if len(data) < s {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[6:s], int8(data[5]), opt)
+		return ret, s, err
+	case 0xd4:
+		if len(data) < 3 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[2:3], int8(data[1]), opt)
+		return ret, 3, err
+	case 0xd5:
+		if len(data) < 4 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[2:4], int8(data[1]), opt)
+		return ret, 4, err
+	case 0xd6:
+		if len(data) < 6 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[2:6], int8(data[1]), opt)
+		return ret, 6, err
+	case 0xd7:
+		if len(data) < 10 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[2:10], int8(data[1]), opt)
+		return ret, 10, err
+	case 0xd8:
+		if len(data) < 18 {
+			return struct{}{}, 0, ErrShortInput
+		}
+		ret, err := decodeNil_ext(data[2:18], int8(data[1]), opt)
+		return ret, 18, err
+	}
+	return struct{}{}, 0, errors.New("unexpected " + DescribeValue(data) + " when expecting nil")
+}
+
 func DecodeTime(data []byte, opt DecodeOptions) (time.Time, int, error) {
 	if len(data) < 6 {
 		return time.Time{}, 0, ErrShortInputForTime
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"strconv"
 	"time"
 	"unsafe"
 )
@@ -13,39 +14,214 @@ var (
 	ErrVoid              = errors.New("tried to decode a void value")
 	ErrShortInput        = errors.New("msgpack data ends unexpectedly")
 	ErrShortInputForTime = errors.New("msgpack data is too short to hold a time")
+	ErrIntOverflow       = errors.New("msgpack uint 64 doesn't fit in an int")
+	ErrLimitExceeded     = errors.New("msgpack data exceeds the configured limits")
 )
 
+// Limits restricts the resources used to decode untrusted input. Zero values mean unlimited.
+type Limits struct {
+	MaxDepth    int
+	MaxElements int
+	MaxBytes    int
+}
+
 type DecodeOptions struct {
-	Dict            *Dict
-	FlavorSelectors map[uint]uint
-	Injections      map[uint][]byte
+	Dict             *Dict
+	FlavorSelectors  map[uint]uint
+	Injections       map[uint][]byte
+	Uint64           bool
+	IntOverflowError bool
+	NonStringKeys    bool
+	ValidateUTF8     bool
+	Limits           Limits
+
+	UnwrapLengthPrefix bool
+
+	// Depth is the current nesting depth, to be compared against Limits.MaxDepth.
+	Depth int
 }
 
 func (d DecodeOptions) Clone() DecodeOptions {
 	return DecodeOptions{
-		Dict:            d.Dict,
-		FlavorSelectors: maps.Clone(d.FlavorSelectors),
-		Injections:      maps.Clone(d.Injections),
+		Dict:             d.Dict,
+		FlavorSelectors:  maps.Clone(d.FlavorSelectors),
+		Injections:       maps.Clone(d.Injections),
+		Uint64:           d.Uint64,
+		IntOverflowError: d.IntOverflowError,
+		NonStringKeys:    d.NonStringKeys,
+		ValidateUTF8:     d.ValidateUTF8,
+		Limits:           d.Limits,
+		Depth:            d.Depth,
+
+		UnwrapLengthPrefix: d.UnwrapLengthPrefix,
+	}
+}
+
+// CheckBytes returns an error if the data is larger than the limits allow.
+func (d DecodeOptions) CheckBytes(data []byte) error {
+	if d.Limits.MaxBytes > 0 && len(data) > d.Limits.MaxBytes {
+		return fmt.Errorf("%w: %d bytes is more than the maximum of %d", ErrLimitExceeded, len(data), d.Limits.MaxBytes)
+	}
+	return nil
+}
+
+// CheckElements returns an error if a map or array header claims more elements than the limits allow, or more than fit in the remaining data.
+// Every element takes at least one byte, so this prevents allocating huge slices and maps for short input.
+func (d DecodeOptions) CheckElements(elements, perElement, remaining int) error {
+	if d.Limits.MaxElements > 0 && elements > d.Limits.MaxElements {
+		return fmt.Errorf("%w: %d elements is more than the maximum of %d", ErrLimitExceeded, elements, d.Limits.MaxElements)
+	}
+	if elements*perElement > remaining {
+		return ErrShortInput
+	}
+	return nil
+}
+
+// Descend increments Depth and returns an error if that exceeds the limits.
+func (d *DecodeOptions) Descend() error {
+	d.Depth++
+	if d.Limits.MaxDepth > 0 && d.Depth > d.Limits.MaxDepth {
+		return fmt.Errorf("%w: nested deeper than the maximum of %d", ErrLimitExceeded, d.Limits.MaxDepth)
+	}
+	return nil
+}
+
+// Ascend decrements Depth. It must be called when leaving a map, array or wrapping extension that was entered after a successful Descend.
+func (d *DecodeOptions) Ascend() {
+	d.Depth--
+}
+
+// OffsetIn returns the position of sub within data, if sub points into data.
+func OffsetIn(data, sub []byte) (int, bool) {
+	if cap(data) == 0 || cap(sub) == 0 {
+		return 0, false
+	}
+	pos := uintptr(unsafe.Pointer(unsafe.SliceData(sub))) - uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+	if pos > uintptr(len(data)) {
+		return 0, false
+	}
+	return int(pos), true
+}
+
+// PrependPath adds a map key or array index (like "[3]") to the front of a path like "addresses[3].street".
+func PrependPath(segment, path string) string {
+	switch {
+	case segment == "":
+		return path
+	case path == "":
+		return segment
+	case path[0] == '[':
+		return segment + path
+	default:
+		return segment + "." + path
+	}
+}
+
+// IndexSegment returns the path segment for an array index.
+func IndexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// DominantFields applies Go's rules for embedded fields like encoding/json: the shallowest field wins, a tagged field beats untagged ones at the same depth and ambiguous fields are dropped.
+// It is shared by Encode, Unmarshal and msgpackgen, so they always agree. describe returns the msgpack name of a field, how deeply it is embedded and whether its name came from a struct tag.
+// The kept fields are returned in their original order, reusing the backing array of fields.
+func DominantFields[F any](fields []F, describe func(F) (name string, depth int, tagged bool)) []F {
+	depths := map[string]int{}
+	counts := map[string]int{}
+	taggedCounts := map[string]int{}
+	for _, f := range fields {
+		name, depth, tagged := describe(f)
+		d, seen := depths[name]
+		switch {
+		case !seen || depth < d:
+			depths[name] = depth
+			counts[name] = 1
+			taggedCounts[name] = 0
+		case depth == d:
+			counts[name]++
+		default:
+			continue
+		}
+		if tagged {
+			taggedCounts[name]++
+		}
+	}
+	ret := fields[:0]
+	for _, f := range fields {
+		name, depth, tagged := describe(f)
+		if depth != depths[name] {
+			continue
+		}
+		if (tagged && taggedCounts[name] == 1) || (taggedCounts[name] == 0 && counts[name] == 1) {
+			ret = append(ret, f)
+		}
 	}
+	return ret
 }
 
 func UnsafeStringCast(data []byte) string {
 	return unsafe.String(unsafe.SliceData(data), len(data))
 }
 
+// SkipMultiple returns the offset after skipping num values starting at offset.
 func SkipMultiple(data []byte, offset, num int) (int, error) {
-	for num > 0 {
-		if len(data) < offset {
-			return 0, ErrShortInput
+	p := SkipProgress{Offset: offset, Pending: num}
+	if err := p.Skip(data); err != nil {
+		return 0, err
+	}
+	return p.Offset, nil
+}
+
+// SkipProgress is the state of skipping values, which can be continued when more data becomes available.
+type SkipProgress struct {
+	// Offset is the position in the data after the values that were skipped so far.
+	Offset int
+	// Pending is the number of values that still need to be skipped.
+	Pending int
+}
+
+// Skip skips the pending values in data, starting at p.Offset.
+// Nested maps and arrays are skipped by adding their elements to Pending rather than by recursing, so deeply nested input can't overflow the stack.
+// If data ends before all values are skipped it returns ErrShortInput, and p points at the first incomplete value so a later call with more data continues from there.
+func (p *SkipProgress) Skip(data []byte) error {
+	for p.Pending > 0 {
+		if len(data) <= p.Offset {
+			return ErrShortInput
+		}
+		if elements, header, ok := containerHeader(data[p.Offset:]); ok {
+			if len(data) < p.Offset+header {
+				return ErrShortInput
+			}
+			p.Offset += header
+			p.Pending += elements - 1
+			continue
 		}
-		c, err := ValueLength(data[offset:])
+		c, err := ValueLength(data[p.Offset:])
 		if err != nil {
-			return 0, err
+			return err
+		}
+		p.Offset += c
+		p.Pending--
+	}
+	return nil
+}
+
+// containerHeader returns the number of values in the map or array at the start of data and the length of its header.
+func containerHeader(data []byte) (int, int, bool) {
+	switch data[0] {
+	case 0xdc, 0xde:
+		if len(data) < 3 {
+			return 0, 3, true
+		}
+	case 0xdd, 0xdf:
+		if len(data) < 5 {
+			return 0, 5, true
 		}
-		offset += c
-		num--
 	}
-	return offset, nil
+	if elements, header, ok := DecodeUnwrappedMapLen(data); ok {
+		return 2 * elements, header, true
+	}
+	return DecodeUnwrappedArrayLen(data)
 }
 
 func decodeString_ext(data []byte, extType int8, opt DecodeOptions) (string, error) {
@@ -100,12 +276,116 @@ func decodeInt_ext(data []byte, extType int8, opt DecodeOptions) (int, error) {
 		ret, _, err := DecodeInt(data[j:], opt)
 		return ret, err
 
+	case 19: // Void
+		return 0, ErrVoid
+
+	case 20: // Injection
+		b, err := DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return 0, err
+		}
+		ret, _, err := DecodeInt(b, opt)
+		return ret, err
+
 	default:
 		extType := extType // Only let it escape in this (unlikely) branch.
 		return 0, fmt.Errorf("unexpected extension %d while expecting int", extType)
 	}
 }
 
+func decodeUint_ext(data []byte, extType int8, opt DecodeOptions) (uint64, error) {
+	switch extType {
+	case 17: // Length-prefixed entry
+		ret, _, err := DecodeUint(data, opt)
+		return ret, err
+
+	case 18: // Flavor pick
+		j, err := DecodeFlavorPick(data, opt)
+		if err != nil {
+			return 0, err
+		}
+		ret, _, err := DecodeUint(data[j:], opt)
+		return ret, err
+
+	case 19: // Void
+		return 0, ErrVoid
+
+	case 20: // Injection
+		b, err := DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return 0, err
+		}
+		ret, _, err := DecodeUint(b, opt)
+		return ret, err
+
+	default:
+		extType := extType // Only let it escape in this (unlikely) branch.
+		return 0, fmt.Errorf("unexpected extension %d while expecting uint", extType)
+	}
+}
+
+func decodeBytes_ext(data []byte, extType int8, opt DecodeOptions) ([]byte, error) {
+	switch extType {
+	case 17: // Length-prefixed entry
+		ret, _, err := DecodeBytes(data, opt)
+		return ret, err
+
+	case 18: // Flavor pick
+		j, err := DecodeFlavorPick(data, opt)
+		if err != nil {
+			return nil, err
+		}
+		ret, _, err := DecodeBytes(data[j:], opt)
+		return ret, err
+
+	case 19: // Void
+		return nil, ErrVoid
+
+	case 20: // Injection
+		b, err := DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return nil, err
+		}
+		ret, _, err := DecodeBytes(b, opt)
+		return ret, err
+
+	default:
+		extType := extType // Only let it escape in this (unlikely) branch.
+		return nil, fmt.Errorf("unexpected extension %d while expecting []byte", extType)
+	}
+}
+
+func decodeNil_ext(data []byte, extType int8, opt DecodeOptions) (struct{}, error) {
+	switch extType {
+	case 17: // Length-prefixed entry
+		ret, _, err := DecodeNil(data, opt)
+		return ret, err
+
+	case 18: // Flavor pick
+		j, err := DecodeFlavorPick(data, opt)
+		if err != nil {
+			return struct{}{}, err
+		}
+		ret, _, err := DecodeNil(data[j:], opt)
+		return ret, err
+
+	case 19: // Void
+		return struct{}{}, ErrVoid
+
+	case 20: // Injection
+		b, err := DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return struct{}{}, err
+		}
+		ret, _, err := DecodeNil(b, opt)
+		return ret, err
+
+	default:
+		extType := extType // Only let it escape in this (unlikely) branch.
+		return struct{}{}, fmt.Errorf("unexpected extension %d while expecting nil", extType)
+	}
+}
+
 func decodeFloat32_ext(data []byte, extType int8, opt DecodeOptions) (float32, error) {
 	switch extType {
 	case 17: // Length-prefixed entry
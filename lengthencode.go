@@ -58,6 +58,7 @@ type lengthEncoderSkip int
 type lengthEncoderHeader *int
 
 func (le *lengthEncoder) parseValue() (int, error) {
+	start := le.offset
 	if l := internal.DecodeLengthPrefixExtension(le.data[le.offset:]); l > 0 {
 		le.appendAction(lengthEncoderSkip(l))
 		le.offset += l
@@ -69,7 +70,7 @@ func (le *lengthEncoder) parseValue() (int, error) {
 		if !ok {
 			sz, err := Size(le.data[le.offset:])
 			if err != nil {
-				return 0, err
+				return 0, decodeErrorAt(err, le.offset, "")
 			}
 			le.offset += sz
 			le.appendCopy(sz)
@@ -84,15 +85,26 @@ func (le *lengthEncoder) parseValue() (int, error) {
 	if isMap {
 		elements *= 2
 	}
+	var keyStart int
 	for i := 0; elements > i; i++ {
+		if isMap && i%2 == 0 {
+			keyStart = le.offset
+		}
 		sz, err := le.parseValue()
 		if err != nil {
-			return 0, err
+			var segment string
+			switch {
+			case !isMap:
+				segment = internal.IndexSegment(i)
+			case i%2 == 1:
+				segment = keySegment(le.data[keyStart:], internal.DecodeOptions{})
+			}
+			return 0, decodeErrorAt(err, 0, segment)
 		}
 		*h += sz
 	}
 	if *h > math.MaxUint32 {
-		return 0, fmt.Errorf("fastmsgpack.LengthEncode: array/map data too long to encode (len %d)", *h)
+		return 0, decodeErrorAt(fmt.Errorf("fastmsgpack.LengthEncode: array/map data too long to encode (len %d)", *h), start, "")
 	}
 	hdrSize := sizeOfLengthHeader(*h)
 	return hdrSize + *h, nil
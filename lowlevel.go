@@ -51,3 +51,27 @@ func SplitMap(data []byte, dict *Dict) ([]string, [][]byte, error) {
 	}
 	return keys, values, nil
 }
+
+// SplitMapRaw splits a msgpack map into the msgpack chunks of its keys and values. Unlike SplitMap it works on any type of key.
+// The returned slices point into the given data.
+func SplitMapRaw(data []byte) ([][]byte, [][]byte, error) {
+	d := NewDecoder(data)
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([][]byte, elements)
+	values := make([][]byte, elements)
+	for i := 0; elements > i; i++ {
+		keys[i], err = d.DecodeRaw()
+		if err != nil {
+			return nil, nil, err
+		}
+		values[i], err = d.DecodeRaw()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return keys, values, nil
+}
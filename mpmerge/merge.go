@@ -66,7 +66,7 @@ func (m StringMap) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDi
 		}
 		values[i], err = dec.DecodeRaw()
 		if err != nil {
-			return nil, err
+			return nil, errorAt(err, 0, k)
 		}
 	}
 	dst, err = internal.AppendMapLen(dst, newSize)
@@ -88,7 +88,7 @@ func (m StringMap) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDi
 		}
 		dst, err = sm.descend(dst, values[i], o, readDict)
 		if err != nil {
-			return nil, err
+			return nil, errorAt(err, offsetIn(data, values[i]), keys[i])
 		}
 	}
 	for k, sm := range m.Changes {
@@ -167,7 +167,7 @@ func (m Array) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *
 		}
 		v, err := dec.DecodeRaw()
 		if err != nil {
-			return nil, err
+			return nil, errorAt(err, 0, internal.IndexSegment(i))
 		}
 		switch sm.(type) {
 		case nil:
@@ -176,14 +176,14 @@ func (m Array) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *
 		default:
 			dst, err = sm.descend(dst, v, o, readDict)
 			if err != nil {
-				return nil, err
+				return nil, errorAt(err, offsetIn(data, v), internal.IndexSegment(i))
 			}
 		}
 	}
 	for i := len(m.Changes); elements > i; i++ {
 		v, err := dec.DecodeRaw()
 		if err != nil {
-			return nil, err
+			return nil, errorAt(err, 0, internal.IndexSegment(i))
 		}
 		dst = append(dst, v...)
 	}
@@ -202,7 +202,7 @@ func (m Each) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *f
 	case fastmsgpack.TypeArray:
 		elements, err = dec.DecodeArrayLen()
 	default:
-		return nil, fmt.Errorf("encountered msgpack type %q while expecting a map or array", t.String())
+		return nil, &fastmsgpack.DecodeError{Err: fmt.Errorf("encountered msgpack type %q while expecting a map or array", t.String())}
 	}
 	if err != nil {
 		return nil, err
@@ -222,12 +222,13 @@ func (m Each) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *f
 		return nil, err
 	}
 	for i := 0; elements > i; i++ {
+		var k []byte
 		if isMap {
-			v, err := dec.DecodeRaw()
+			k, err = dec.DecodeRaw()
 			if err != nil {
 				return nil, err
 			}
-			dst = append(dst, v...)
+			dst = append(dst, k...)
 		}
 		v, err := dec.DecodeRaw()
 		if err != nil {
@@ -235,7 +236,11 @@ func (m Each) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *f
 		}
 		dst, err = m.Change.descend(dst, v, o, readDict)
 		if err != nil {
-			return nil, err
+			segment := internal.IndexSegment(i)
+			if isMap {
+				segment, _ = fastmsgpack.NewDecoder(k, fastmsgpack.WithDict(readDict)).DecodeString()
+			}
+			return nil, errorAt(err, offsetIn(data, v), segment)
 		}
 	}
 	return dst, nil
@@ -252,3 +257,20 @@ func (m EncodedValue) descend(dst, data []byte, o fastmsgpack.EncodeOptions, rea
 func (DeleteEntry) descend(dst, data []byte, o fastmsgpack.EncodeOptions, readDict *fastmsgpack.Dict) ([]byte, error) {
 	return nil, errors.New("fastmsgpack/mpmerge: DeleteEntry must be used as a child of a map or array")
 }
+
+// errorAt moves a *fastmsgpack.DecodeError by offset and prepends the path segment.
+func errorAt(err error, offset int, segment string) error {
+	de, ok := err.(*fastmsgpack.DecodeError)
+	if !ok {
+		return err
+	}
+	de.Offset += offset
+	de.Path = internal.PrependPath(segment, de.Path)
+	return de
+}
+
+// offsetIn returns where value starts in data.
+func offsetIn(data, value []byte) int {
+	offset, _ := internal.OffsetIn(data, value)
+	return offset
+}
@@ -191,6 +191,9 @@ func (c JSONConverter) Convert(dst io.Writer, data []byte, opts ...fastmsgpack.D
 	}
 	cc.encodedDict = ensureDictPrepared(cc.options)
 	defer release()
+	if err := cc.options.CheckBytes(data); err != nil {
+		return err
+	}
 	if _, err := cc.convertValue(data); err != nil {
 		return err
 	}
@@ -232,6 +235,10 @@ func getReusableConverter(dst io.Writer) (*converter, func()) {
 }
 
 func (c *converter) convertValue_array(data []byte, offset, elements int) (int, error) {
+	if err := c.descend(elements, 1, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer c.options.Ascend()
 	if err := c.writeByte('['); err != nil {
 		return 0, err
 	}
@@ -258,6 +265,10 @@ func (c *converter) convertValue_array(data []byte, offset, elements int) (int,
 }
 
 func (c *converter) convertValue_map(data []byte, offset, elements int) (int, error) {
+	if err := c.descend(elements, 2, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer c.options.Ascend()
 	if err := c.writeByte('{'); err != nil {
 		return 0, err
 	}
@@ -300,6 +311,14 @@ func (c *converter) convertValue_map(data []byte, offset, elements int) (int, er
 	return offset, c.writeByte('}')
 }
 
+// descend checks the limits when entering a map, array or wrapping extension. options.Ascend must be called when leaving it.
+func (c *converter) descend(elements, perElement, remaining int) error {
+	if err := c.options.Descend(); err != nil {
+		return err
+	}
+	return c.options.CheckElements(elements, perElement, remaining)
+}
+
 func (c *converter) write(b []byte) error {
 	switch c.transactionalState {
 	case transactionalStateNormal:
@@ -361,6 +380,13 @@ func (c *converter) appendFloat64(f float64) error {
 }
 
 func (c *converter) convertValue_ext(data []byte, extType int8) error {
+	switch extType {
+	case 17, 18, 20:
+		if err := c.descend(0, 0, 0); err != nil {
+			return err
+		}
+		defer c.options.Ascend()
+	}
 	switch extType {
 	case -1:
 		ts, err := internal.DecodeTimestamp(data)
@@ -0,0 +1,843 @@
+// Command msgpackgen generates DecodeMsgpack, AppendMsgpack and AppendMsgpackWithOptions methods for structs, so they can be decoded and encoded with fastmsgpack without reflection.
+//
+// Typical usage is through go:generate:
+//
+//	//go:generate go run github.com/hexon/fastmsgpack/msgpackgen -type=Person,Address
+//
+// Fields are named after the `msgpack:"name,omitempty"` struct tag (or the Go field name) just like with fastmsgpack.Unmarshal.
+// Types the generator doesn't know how to handle fall back to Decoder.Unmarshal and EncodeOptions.Encode.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default srcdir/<type>_msgpack.go")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("msgpackgen: ")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeNames, ",")
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_msgpack.go")
+	}
+
+	pkg, err := loadPackage(dir, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := &generator{
+		pkg:      pkg,
+		prefix:   "_" + names[0] + "_",
+		imports:  map[string]string{"github.com/hexon/fastmsgpack": "fastmsgpack"},
+		helpers:  map[string]string{},
+		generate: map[*types.TypeName]bool{},
+	}
+	var targets []*types.Named
+	for _, n := range names {
+		obj, ok := pkg.Scope().Lookup(n).(*types.TypeName)
+		if !ok {
+			log.Fatalf("type %s not found in package %s", n, pkg.Name())
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			log.Fatalf("%s is not a named type", n)
+		}
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			log.Fatalf("%s is not a struct", n)
+		}
+		g.generate[obj] = true
+		targets = append(targets, named)
+	}
+	for _, t := range targets {
+		if err := g.generateDecoder(t); err != nil {
+			log.Fatal(err)
+		}
+		if err := g.generateEncoder(t); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"msgpackgen %s\". DO NOT EDIT.\n", strings.Join(os.Args[1:], " "))
+	fmt.Fprintf(&out, "\n")
+	fmt.Fprintf(&out, "package %s\n", pkg.Name())
+	fmt.Fprintf(&out, "\n")
+	fmt.Fprintf(&out, "import (\n")
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		// Standard library imports go first.
+		iStd := !strings.Contains(strings.Split(paths[i], "/")[0], ".")
+		jStd := !strings.Contains(strings.Split(paths[j], "/")[0], ".")
+		if iStd != jStd {
+			return iStd
+		}
+		return paths[i] < paths[j]
+	})
+	for i, p := range paths {
+		if i > 0 && strings.Contains(strings.Split(p, "/")[0], ".") && !strings.Contains(strings.Split(paths[i-1], "/")[0], ".") {
+			fmt.Fprintf(&out, "\n")
+		}
+		if g.imports[p] == filepath.Base(p) {
+			fmt.Fprintf(&out, "	%q\n", p)
+		} else {
+			fmt.Fprintf(&out, "	%s %q\n", g.imports[p], p)
+		}
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(g.buf.Bytes())
+	out.Write(g.helperBuf.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Printf("generated invalid Go code: %v", err)
+		formatted = out.Bytes()
+	}
+	if err := os.WriteFile(outputName, formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadPackage(dir, skipFile string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	skipAbs, err := filepath.Abs(skipFile)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, fn := range bp.GoFiles {
+		path := filepath.Join(dir, fn)
+		if abs, err := filepath.Abs(path); err == nil && abs == skipAbs {
+			// Don't let a stale generated file influence the result.
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {}, // Methods of the types we generate for might be missing.
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("failed to type check %s", dir)
+	}
+	return pkg, nil
+}
+
+type generator struct {
+	buf       bytes.Buffer
+	helperBuf bytes.Buffer
+	pkg       *types.Package
+	prefix    string
+	imports   map[string]string
+	helpers   map[string]string
+	generate  map[*types.TypeName]bool
+}
+
+type field struct {
+	name      string
+	path      string
+	typ       types.Type
+	omitEmpty bool
+	depth     int
+	// tagged is set if the name came from the msgpack tag.
+	tagged bool
+}
+
+func (g *generator) collectFields(s *types.Struct, path string, depth int) ([]field, error) {
+	var ret []field
+	for i := 0; s.NumFields() > i; i++ {
+		v := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i)).Get("msgpack")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if v.Embedded() && name == "" {
+			if st, ok := v.Type().Underlying().(*types.Struct); ok && !isTime(v.Type()) {
+				sub, err := g.collectFields(st, path+"."+v.Name(), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, sub...)
+				continue
+			}
+			if _, ok := v.Type().(*types.Pointer); ok {
+				return nil, fmt.Errorf("embedded pointer field %s is not supported", v.Name())
+			}
+		}
+		if !v.Exported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = v.Name()
+		}
+		ret = append(ret, field{
+			name:      name,
+			path:      path + "." + v.Name(),
+			typ:       v.Type(),
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+			depth:     depth,
+			tagged:    tagged,
+		})
+	}
+	return ret, nil
+}
+
+// dominantFields applies Go's rules for embedded fields with internal.DominantFields, so generated code picks the same fields as fastmsgpack.Unmarshal.
+func dominantFields(fields []field) []field {
+	return internal.DominantFields(fields, func(f field) (string, int, bool) {
+		return f.name, f.depth, f.tagged
+	})
+}
+
+func (g *generator) fields(t *types.Named) ([]field, error) {
+	fields, err := g.collectFields(t.Underlying().(*types.Struct), "x", 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Obj().Name(), err)
+	}
+	return dominantFields(fields), nil
+}
+
+// asArray reports whether t has a field like _msgpack struct{} with tag msgpack:",as_array", which makes it encode as an array of its field values.
+func asArray(t *types.Named) bool {
+	s := t.Underlying().(*types.Struct)
+	for i := 0; s.NumFields() > i; i++ {
+		if s.Field(i).Name() != "_msgpack" {
+			continue
+		}
+		_, opts, _ := strings.Cut(reflect.StructTag(s.Tag(i)).Get("msgpack"), ",")
+		return strings.Contains(","+opts+",", ",as_array,")
+	}
+	return false
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+func (g *generator) generateDecoder(t *types.Named) error {
+	fields, err := g.fields(t)
+	if err != nil {
+		return err
+	}
+	w := &g.buf
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "// DecodeMsgpack decodes the next value from d into x. It implements the interface used by fastmsgpack.Unmarshal.\n")
+	fmt.Fprintf(w, "func (x *%s) DecodeMsgpack(d *fastmsgpack.Decoder) error {\n", t.Obj().Name())
+	if asArray(t) {
+		fmt.Fprintf(w, "	if d.PeekType() == fastmsgpack.TypeArray {\n")
+		fmt.Fprintf(w, "		elements, err := d.DecodeArrayLen()\n")
+		fmt.Fprintf(w, "		if err != nil {\n")
+		fmt.Fprintf(w, "			return err\n")
+		fmt.Fprintf(w, "		}\n")
+		fmt.Fprintf(w, "		for i := 0; elements > i; i++ {\n")
+		fmt.Fprintf(w, "			switch i {\n")
+		for i, f := range fields {
+			fmt.Fprintf(w, "			case %d:\n", i)
+			g.decodeInto(w, f.typ, f.path)
+		}
+		fmt.Fprintf(w, "			default:\n")
+		fmt.Fprintf(w, "				err = d.Skip()\n")
+		fmt.Fprintf(w, "			}\n")
+		fmt.Fprintf(w, "			if err == fastmsgpack.ErrVoid {\n")
+		fmt.Fprintf(w, "				err = d.Skip()\n")
+		fmt.Fprintf(w, "			}\n")
+		fmt.Fprintf(w, "			if err != nil {\n")
+		fmt.Fprintf(w, "				return err\n")
+		fmt.Fprintf(w, "			}\n")
+		fmt.Fprintf(w, "		}\n")
+		fmt.Fprintf(w, "		return nil\n")
+		fmt.Fprintf(w, "	}\n")
+	}
+	fmt.Fprintf(w, "	elements, err := d.DecodeMapLen()\n")
+	fmt.Fprintf(w, "	if err != nil {\n")
+	fmt.Fprintf(w, "		return err\n")
+	fmt.Fprintf(w, "	}\n")
+	fmt.Fprintf(w, "	for i := 0; elements > i; i++ {\n")
+	fmt.Fprintf(w, "		k, err := d.DecodeString()\n")
+	fmt.Fprintf(w, "		if err != nil {\n")
+	fmt.Fprintf(w, "			if err == fastmsgpack.ErrVoid {\n")
+	fmt.Fprintf(w, "				if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(w, "					return err\n")
+	fmt.Fprintf(w, "				}\n")
+	fmt.Fprintf(w, "				if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(w, "					return err\n")
+	fmt.Fprintf(w, "				}\n")
+	fmt.Fprintf(w, "				continue\n")
+	fmt.Fprintf(w, "			}\n")
+	fmt.Fprintf(w, "			return err\n")
+	fmt.Fprintf(w, "		}\n")
+	fmt.Fprintf(w, "		switch k {\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "		case %q:\n", f.name)
+		g.decodeInto(w, f.typ, f.path)
+	}
+	fmt.Fprintf(w, "		default:\n")
+	fmt.Fprintf(w, "			err = d.Skip()\n")
+	fmt.Fprintf(w, "		}\n")
+	fmt.Fprintf(w, "		if err == fastmsgpack.ErrVoid {\n")
+	fmt.Fprintf(w, "			err = d.Skip()\n")
+	fmt.Fprintf(w, "		}\n")
+	fmt.Fprintf(w, "		if err != nil {\n")
+	fmt.Fprintf(w, "			return err\n")
+	fmt.Fprintf(w, "		}\n")
+	fmt.Fprintf(w, "	}\n")
+	fmt.Fprintf(w, "	return nil\n")
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+func (g *generator) generateEncoder(t *types.Named) error {
+	fields, err := g.fields(t)
+	if err != nil {
+		return err
+	}
+	w := &g.buf
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "// AppendMsgpack appends the msgpack representation of x to dst with the default EncodeOptions.\n")
+	fmt.Fprintf(w, "func (x %s) AppendMsgpack(dst []byte) ([]byte, error) {\n", t.Obj().Name())
+	fmt.Fprintf(w, "	return x.AppendMsgpackWithOptions(dst, fastmsgpack.EncodeOptions{})\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "// AppendMsgpackWithOptions appends the msgpack representation of x to dst. It implements the interface used by fastmsgpack.Encode.\n")
+	fmt.Fprintf(w, "func (x %s) AppendMsgpackWithOptions(dst []byte, o fastmsgpack.EncodeOptions) ([]byte, error) {\n", t.Obj().Name())
+	fmt.Fprintf(w, "	var err error\n")
+	if asArray(t) {
+		fmt.Fprintf(w, "	dst, err = o.EncodeArrayLen(dst, %d)\n", len(fields))
+		fmt.Fprintf(w, "	if err != nil {\n")
+		fmt.Fprintf(w, "		return nil, err\n")
+		fmt.Fprintf(w, "	}\n")
+		for _, f := range fields {
+			g.appendValue(w, f.typ, f.path)
+		}
+		fmt.Fprintf(w, "	return dst, nil\n")
+		fmt.Fprintf(w, "}\n")
+		return nil
+	}
+	var fixed int
+	for _, f := range fields {
+		if !f.omitEmpty {
+			fixed++
+		}
+	}
+	fmt.Fprintf(w, "	n := %d\n", fixed)
+	for _, f := range fields {
+		if f.omitEmpty {
+			if cond := g.nonEmptyCondition(f.typ, f.path); cond != "" {
+				fmt.Fprintf(w, "	if %s {\n", cond)
+				fmt.Fprintf(w, "		n++\n")
+				fmt.Fprintf(w, "	}\n")
+			} else {
+				fmt.Fprintf(w, "	n++\n")
+			}
+		}
+	}
+	fmt.Fprintf(w, "	dst, err = o.EncodeMapLen(dst, n)\n")
+	fmt.Fprintf(w, "	if err != nil {\n")
+	fmt.Fprintf(w, "		return nil, err\n")
+	fmt.Fprintf(w, "	}\n")
+	for _, f := range fields {
+		cond := ""
+		if f.omitEmpty {
+			cond = g.nonEmptyCondition(f.typ, f.path)
+		}
+		if cond != "" {
+			fmt.Fprintf(w, "	if %s {\n", cond)
+		}
+		fmt.Fprintf(w, "	dst, err = o.EncodeString(dst, %q)\n", f.name)
+		fmt.Fprintf(w, "	if err != nil {\n")
+		fmt.Fprintf(w, "		return nil, err\n")
+		fmt.Fprintf(w, "	}\n")
+		if p, ok := f.typ.(*types.Pointer); ok && cond != "" {
+			// We already know it isn't nil.
+			g.appendValue(w, p.Elem(), "*"+f.path)
+		} else {
+			g.appendValue(w, f.typ, f.path)
+		}
+		if cond != "" {
+			fmt.Fprintf(w, "	}\n")
+		}
+	}
+	fmt.Fprintf(w, "	return dst, nil\n")
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+func isTime(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
+}
+
+func (g *generator) hasMethod(t types.Type, name string) bool {
+	if n, ok := t.(*types.Named); ok && g.generate[n.Obj()] {
+		return true
+	}
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, g.pkg, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func isByteSlice(t types.Type) bool {
+	s, ok := t.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+	b, ok := s.Elem().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+func (g *generator) zero(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		default:
+			return "0"
+		}
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface, *types.Chan, *types.Signature:
+		return "nil"
+	default:
+		return g.typeString(t) + "{}"
+	}
+}
+
+// helperName returns the name of a (to be) generated helper function for the given type.
+func (g *generator) helperName(kind string, t types.Type, emit func(name string)) string {
+	key := kind + " " + g.typeString(t)
+	if name, ok := g.helpers[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("%s%s%d", g.prefix, kind, len(g.helpers))
+	g.helpers[key] = name
+	emit(name)
+	return name
+}
+
+// decodeInto emits code that decodes the next value into target and sets err.
+// If err is set to fastmsgpack.ErrVoid, nothing was consumed and target is unchanged.
+func (g *generator) decodeInto(w *bytes.Buffer, t types.Type, target string) {
+	ts := g.typeString(t)
+	fmt.Fprintf(w, "if d.PeekType() == fastmsgpack.TypeNil {\n")
+	fmt.Fprintf(w, "	%s = %s\n", target, g.zero(t))
+	fmt.Fprintf(w, "	err = d.Skip()\n")
+	fmt.Fprintf(w, "} else {\n")
+	fmt.Fprintf(w, "	var v %s\n", ts)
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		var method string
+		switch {
+		case u.Kind() == types.Bool:
+			method = "DecodeBool"
+		case u.Kind() == types.String:
+			method = "DecodeString"
+		case u.Kind() == types.Float32:
+			method = "DecodeFloat32"
+		case u.Kind() == types.Float64:
+			method = "DecodeFloat64"
+		case u.Info()&types.IsInteger != 0:
+			method = "DecodeInt"
+		}
+		if method == "" {
+			g.decodeFallback(w)
+			break
+		}
+		switch {
+		case u.Info()&types.IsUnsigned != 0:
+			fmt.Fprintf(w, "	var n uint64\n")
+			fmt.Fprintf(w, "	if n, err = d.DecodeUint(); err == nil {\n")
+			if check := overflowCheck(u.Kind()); check != "" {
+				g.imports["fmt"] = "fmt"
+				fmt.Fprintf(w, "		if %s {\n", check)
+				fmt.Fprintf(w, "			err = fmt.Errorf(\"fastmsgpack: %%d overflows %s\", n)\n", ts)
+				fmt.Fprintf(w, "		}\n")
+			}
+			fmt.Fprintf(w, "		v = %s\n", convert(t, types.Typ[types.Uint64], "n", ts))
+			fmt.Fprintf(w, "	}\n")
+		case u.Info()&types.IsInteger != 0:
+			g.imports["errors"] = "errors"
+			g.imports["fmt"] = "fmt"
+			fmt.Fprintf(w, "	var n int\n")
+			fmt.Fprintf(w, "	if n, err = d.DecodeIntChecked(); err == nil {\n")
+			if check := overflowCheck(u.Kind()); check != "" {
+				fmt.Fprintf(w, "		if %s {\n", check)
+				fmt.Fprintf(w, "			err = fmt.Errorf(\"fastmsgpack: %%d overflows %s\", n)\n", ts)
+				fmt.Fprintf(w, "		}\n")
+			}
+			fmt.Fprintf(w, "		v = %s\n", convert(t, types.Typ[types.Int], "n", ts))
+			// A uint 64 above math.MaxInt64 must not wrap around to a negative number.
+			fmt.Fprintf(w, "	} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {\n")
+			fmt.Fprintf(w, "		u, _ := d.DecodeUint()\n")
+			fmt.Fprintf(w, "		err = fmt.Errorf(\"fastmsgpack: %%d overflows %s\", u)\n", ts)
+			fmt.Fprintf(w, "	}\n")
+		default:
+			if types.Identical(t, types.Typ[u.Kind()]) {
+				fmt.Fprintf(w, "	v, err = d.%s()\n", method)
+			} else {
+				fmt.Fprintf(w, "	var b %s\n", u.Name())
+				fmt.Fprintf(w, "	if b, err = d.%s(); err == nil {\n", method)
+				fmt.Fprintf(w, "		v = %s(b)\n", ts)
+				fmt.Fprintf(w, "	}\n")
+			}
+		}
+
+	case *types.Slice:
+		if isByteSlice(t) {
+			if types.Identical(t, types.NewSlice(types.Typ[types.Byte])) {
+				fmt.Fprintf(w, "	v, err = d.DecodeBytes()\n")
+			} else {
+				fmt.Fprintf(w, "	var b []byte\n")
+				fmt.Fprintf(w, "	if b, err = d.DecodeBytes(); err == nil {\n")
+				fmt.Fprintf(w, "		v = %s(b)\n", ts)
+				fmt.Fprintf(w, "	}\n")
+			}
+			break
+		}
+		name := g.helperName("decodeSlice", t, func(name string) { g.emitSliceDecoder(name, t, u) })
+		fmt.Fprintf(w, "	v, err = %s(d)\n", name)
+
+	case *types.Map:
+		name := g.helperName("decodeMap", t, func(name string) { g.emitMapDecoder(name, t, u) })
+		fmt.Fprintf(w, "	v, err = %s(d)\n", name)
+
+	case *types.Pointer:
+		name := g.helperName("decodePointer", t, func(name string) { g.emitPointerDecoder(name, t, u) })
+		fmt.Fprintf(w, "	v, err = %s(d)\n", name)
+
+	case *types.Interface:
+		if !u.Empty() {
+			g.decodeFallback(w)
+			break
+		}
+		fmt.Fprintf(w, "	v, err = d.DecodeValue()\n")
+
+	default:
+		switch {
+		case isTime(t):
+			fmt.Fprintf(w, "	v, err = d.DecodeTime()\n")
+		case g.hasMethod(t, "DecodeMsgpack"):
+			fmt.Fprintf(w, "	err = v.DecodeMsgpack(d)\n")
+		default:
+			g.decodeFallback(w)
+		}
+	}
+	fmt.Fprintf(w, "	if err == nil {\n")
+	fmt.Fprintf(w, "		%s = v\n", target)
+	fmt.Fprintf(w, "	}\n")
+	fmt.Fprintf(w, "}\n")
+}
+
+// convert returns expr converted from type from to type to (named toName), or expr itself if no conversion is needed.
+func convert(from, to types.Type, expr, toName string) string {
+	if types.Identical(from, to) {
+		return expr
+	}
+	return toName + "(" + expr + ")"
+}
+
+func (g *generator) decodeFallback(w *bytes.Buffer) {
+	fmt.Fprintf(w, "	err = d.Unmarshal(&v)\n")
+}
+
+func overflowCheck(k types.BasicKind) string {
+	switch k {
+	case types.Int8:
+		return "n < -1<<7 || n > 1<<7-1"
+	case types.Int16:
+		return "n < -1<<15 || n > 1<<15-1"
+	case types.Int32:
+		return "n < -1<<31 || n > 1<<31-1"
+	case types.Uint8:
+		return "n > 1<<8-1"
+	case types.Uint16:
+		return "n > 1<<16-1"
+	case types.Uint32:
+		return "n > 1<<32-1"
+	default:
+		return ""
+	}
+}
+
+func (g *generator) emitSliceDecoder(name string, t types.Type, s *types.Slice) {
+	var w bytes.Buffer
+	ts := g.typeString(t)
+	fmt.Fprintf(&w, "\n")
+	fmt.Fprintf(&w, "func %s(d *fastmsgpack.Decoder) (%s, error) {\n", name, ts)
+	fmt.Fprintf(&w, "	elements, err := d.DecodeArrayLen()\n")
+	fmt.Fprintf(&w, "	if err != nil {\n")
+	fmt.Fprintf(&w, "		return nil, err\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	ret := make(%s, 0, elements)\n", ts)
+	fmt.Fprintf(&w, "	for i := 0; elements > i; i++ {\n")
+	fmt.Fprintf(&w, "		var e %s\n", g.typeString(s.Elem()))
+	g.decodeInto(&w, s.Elem(), "e")
+	fmt.Fprintf(&w, "		if err == fastmsgpack.ErrVoid {\n")
+	fmt.Fprintf(&w, "			if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(&w, "				return nil, err\n")
+	fmt.Fprintf(&w, "			}\n")
+	fmt.Fprintf(&w, "			continue\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		if err != nil {\n")
+	fmt.Fprintf(&w, "			return nil, err\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		ret = append(ret, e)\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	return ret, nil\n")
+	fmt.Fprintf(&w, "}\n")
+	g.helperBuf.Write(w.Bytes())
+}
+
+func (g *generator) emitMapDecoder(name string, t types.Type, m *types.Map) {
+	var w bytes.Buffer
+	ts := g.typeString(t)
+	fmt.Fprintf(&w, "\n")
+	fmt.Fprintf(&w, "func %s(d *fastmsgpack.Decoder) (%s, error) {\n", name, ts)
+	fmt.Fprintf(&w, "	elements, err := d.DecodeMapLen()\n")
+	fmt.Fprintf(&w, "	if err != nil {\n")
+	fmt.Fprintf(&w, "		return nil, err\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	ret := make(%s, elements)\n", ts)
+	fmt.Fprintf(&w, "	for i := 0; elements > i; i++ {\n")
+	fmt.Fprintf(&w, "		var k %s\n", g.typeString(m.Key()))
+	g.decodeInto(&w, m.Key(), "k")
+	fmt.Fprintf(&w, "		if err == fastmsgpack.ErrVoid {\n")
+	fmt.Fprintf(&w, "			if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(&w, "				return nil, err\n")
+	fmt.Fprintf(&w, "			}\n")
+	fmt.Fprintf(&w, "			if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(&w, "				return nil, err\n")
+	fmt.Fprintf(&w, "			}\n")
+	fmt.Fprintf(&w, "			continue\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		if err != nil {\n")
+	fmt.Fprintf(&w, "			return nil, err\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		var e %s\n", g.typeString(m.Elem()))
+	g.decodeInto(&w, m.Elem(), "e")
+	fmt.Fprintf(&w, "		if err == fastmsgpack.ErrVoid {\n")
+	fmt.Fprintf(&w, "			if err := d.Skip(); err != nil {\n")
+	fmt.Fprintf(&w, "				return nil, err\n")
+	fmt.Fprintf(&w, "			}\n")
+	fmt.Fprintf(&w, "			continue\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		if err != nil {\n")
+	fmt.Fprintf(&w, "			return nil, err\n")
+	fmt.Fprintf(&w, "		}\n")
+	fmt.Fprintf(&w, "		ret[k] = e\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	return ret, nil\n")
+	fmt.Fprintf(&w, "}\n")
+	g.helperBuf.Write(w.Bytes())
+}
+
+func (g *generator) emitPointerDecoder(name string, t types.Type, p *types.Pointer) {
+	var w bytes.Buffer
+	fmt.Fprintf(&w, "\n")
+	fmt.Fprintf(&w, "func %s(d *fastmsgpack.Decoder) (%s, error) {\n", name, g.typeString(t))
+	fmt.Fprintf(&w, "	var err error\n")
+	fmt.Fprintf(&w, "	var e %s\n", g.typeString(p.Elem()))
+	g.decodeInto(&w, p.Elem(), "e")
+	fmt.Fprintf(&w, "	if err != nil {\n")
+	fmt.Fprintf(&w, "		return nil, err\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	return &e, nil\n")
+	fmt.Fprintf(&w, "}\n")
+	g.helperBuf.Write(w.Bytes())
+}
+
+// nonEmptyCondition returns the condition under which an omitempty field is encoded, or "" if it is always encoded.
+func (g *generator) nonEmptyCondition(t types.Type, expr string) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return expr + ` != ""`
+		case u.Info()&types.IsBoolean != 0:
+			return expr
+		case u.Info()&types.IsNumeric != 0:
+			return expr + " != 0"
+		}
+	case *types.Slice, *types.Map:
+		return "len(" + expr + ") != 0"
+	case *types.Array:
+		if u.Len() == 0 {
+			return "false"
+		}
+	case *types.Pointer, *types.Interface:
+		return expr + " != nil"
+	}
+	if g.hasIsZero(t) {
+		return "!" + expr + ".IsZero()"
+	}
+	return ""
+}
+
+// hasIsZero reports whether t has an IsZero() bool method, like time.Time.
+func (g *generator) hasIsZero(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, g.pkg, "IsZero")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	b, ok := sig.Results().At(0).Type().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Bool
+}
+
+// appendValue emits code that appends the msgpack encoding of expr to dst.
+func (g *generator) appendValue(w *bytes.Buffer, t types.Type, expr string) {
+	ts := g.typeString(t)
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Kind() == types.Bool:
+			fmt.Fprintf(w, "dst = o.EncodeBool(dst, %s)\n", convert(t, types.Typ[types.Bool], expr, "bool"))
+			return
+		case u.Kind() == types.String:
+			fmt.Fprintf(w, "dst, err = o.EncodeString(dst, %s)\n", convert(t, types.Typ[types.String], expr, "string"))
+		case u.Kind() == types.Float32:
+			fmt.Fprintf(w, "dst = o.EncodeFloat32(dst, %s)\n", convert(t, types.Typ[types.Float32], expr, "float32"))
+			return
+		case u.Kind() == types.Float64:
+			fmt.Fprintf(w, "dst = o.EncodeFloat64(dst, %s)\n", convert(t, types.Typ[types.Float64], expr, "float64"))
+			return
+		case u.Info()&types.IsUnsigned != 0:
+			fmt.Fprintf(w, "dst = o.EncodeUint(dst, %s)\n", convert(t, types.Typ[types.Uint], expr, "uint"))
+			return
+		case u.Info()&types.IsInteger != 0:
+			fmt.Fprintf(w, "dst = o.EncodeInt(dst, %s)\n", convert(t, types.Typ[types.Int], expr, "int"))
+			return
+		default:
+			fmt.Fprintf(w, "dst, err = o.Encode(dst, %s)\n", expr)
+		}
+
+	case *types.Slice:
+		if isByteSlice(t) {
+			fmt.Fprintf(w, "dst, err = o.EncodeBytes(dst, %s)\n", expr)
+			break
+		}
+		name := g.helperName("appendSlice", t, func(name string) { g.emitSliceEncoder(name, ts, u) })
+		fmt.Fprintf(w, "dst, err = %s(o, dst, %s)\n", name, expr)
+
+	case *types.Map:
+		name := g.helperName("appendMap", t, func(name string) { g.emitMapEncoder(name, ts, u) })
+		fmt.Fprintf(w, "dst, err = %s(o, dst, %s)\n", name, expr)
+
+	case *types.Pointer:
+		fmt.Fprintf(w, "if %s == nil {\n", expr)
+		fmt.Fprintf(w, "	dst = o.EncodeNil(dst)\n")
+		fmt.Fprintf(w, "} else {\n")
+		g.appendValue(w, u.Elem(), "*"+expr)
+		fmt.Fprintf(w, "}\n")
+		return
+
+	default:
+		switch {
+		case isTime(t):
+			fmt.Fprintf(w, "dst = o.EncodeTime(dst, %s)\n", expr)
+			return
+		case g.hasMethod(t, "AppendMsgpackWithOptions"):
+			if strings.HasPrefix(expr, "*") {
+				expr = "(" + expr + ")"
+			}
+			fmt.Fprintf(w, "dst, err = %s.AppendMsgpackWithOptions(dst, o)\n", expr)
+		case g.hasMethod(t, "AppendMsgpack"):
+			if strings.HasPrefix(expr, "*") {
+				expr = "(" + expr + ")"
+			}
+			fmt.Fprintf(w, "dst, err = %s.AppendMsgpack(dst)\n", expr)
+		default:
+			fmt.Fprintf(w, "dst, err = o.Encode(dst, %s)\n", expr)
+		}
+	}
+	fmt.Fprintf(w, "if err != nil {\n")
+	fmt.Fprintf(w, "	return nil, err\n")
+	fmt.Fprintf(w, "}\n")
+}
+
+func (g *generator) emitSliceEncoder(name, ts string, s *types.Slice) {
+	var w bytes.Buffer
+	fmt.Fprintf(&w, "\n")
+	fmt.Fprintf(&w, "func %s(o fastmsgpack.EncodeOptions, dst []byte, s %s) ([]byte, error) {\n", name, ts)
+	fmt.Fprintf(&w, "	dst, err := o.EncodeArrayLen(dst, len(s))\n")
+	fmt.Fprintf(&w, "	if err != nil {\n")
+	fmt.Fprintf(&w, "		return nil, err\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	for i := range s {\n")
+	g.appendValue(&w, s.Elem(), "s[i]")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	return dst, nil\n")
+	fmt.Fprintf(&w, "}\n")
+	g.helperBuf.Write(w.Bytes())
+}
+
+func (g *generator) emitMapEncoder(name, ts string, m *types.Map) {
+	var w bytes.Buffer
+	fmt.Fprintf(&w, "\n")
+	fmt.Fprintf(&w, "func %s(o fastmsgpack.EncodeOptions, dst []byte, m %s) ([]byte, error) {\n", name, ts)
+	fmt.Fprintf(&w, "	dst, err := o.EncodeMapLen(dst, len(m))\n")
+	fmt.Fprintf(&w, "	if err != nil {\n")
+	fmt.Fprintf(&w, "		return nil, err\n")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	for k, v := range m {\n")
+	g.appendValue(&w, m.Key(), "k")
+	g.appendValue(&w, m.Elem(), "v")
+	fmt.Fprintf(&w, "	}\n")
+	fmt.Fprintf(&w, "	return dst, nil\n")
+	fmt.Fprintf(&w, "}\n")
+	g.helperBuf.Write(w.Bytes())
+}
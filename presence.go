@@ -0,0 +1,93 @@
+package fastmsgpack
+
+import (
+	"fmt"
+
+	"github.com/Jille/genericz/slicez"
+)
+
+// Presence tells which fields were found by ResolveWithPresence.
+type Presence struct {
+	found  []uint64
+	voided []uint64
+}
+
+func newPresence(numFields int) *Presence {
+	words := (numFields + 63) / 64
+	return &Presence{
+		found:  make([]uint64, words),
+		voided: make([]uint64, words),
+	}
+}
+
+// mark records the outcome of resolving field i.
+func (p *Presence) mark(i int, err error) {
+	switch err {
+	case nil:
+		p.found[i/64] |= 1 << (i % 64)
+	case ErrVoid:
+		p.voided[i/64] |= 1 << (i % 64)
+	}
+}
+
+// Found returns whether field i was present in the data. Fields that are explicitly nil are found; void fields are not.
+func (p *Presence) Found(i int) bool {
+	return p.found[i/64]&(1<<(i%64)) != 0
+}
+
+// Void returns whether field i was present in the data as a void value (extension 19), which is treated as if it were missing.
+func (p *Presence) Void(i int) bool {
+	return p.voided[i/64]&(1<<(i%64)) != 0
+}
+
+// Missing returns whether field i was neither found nor void.
+func (p *Presence) Missing(i int) bool {
+	return !p.Found(i) && !p.Void(i)
+}
+
+// ResolveWithPresence is like Resolve, but also tells which fields were found. This allows to distinguish missing fields from fields that are explicitly nil.
+// Array resolvers and wildcards are found if the array was found.
+func (r *Resolver) ResolveWithPresence(data []byte, opts ...DecodeOption) ([]any, *Presence, error) {
+	rc := resolveCall{
+		decoder:  NewDecoder(data, slicez.Concat(r.decodeOptions, opts)...),
+		result:   make([]any, r.numFields),
+		presence: newPresence(r.numFields),
+	}
+	if err := r.run(&rc, data); err != nil {
+		return nil, nil, err
+	}
+	return rc.result, rc.presence, nil
+}
+
+type requiredField struct {
+	index int
+	field string
+}
+
+// Require marks the fields with the given indexes (as returned by Resolve) as required. Resolving data in which any of them is missing or void returns a *MissingFieldError.
+// Like AddArrayResolver, it can not be called concurrently with itself or Resolve.
+func (r *Resolver) Require(indexes ...int) error {
+	fields, subs := r.Describe()
+	names := make([]string, r.numFields)
+	copy(names, fields)
+	for path, sd := range subs {
+		names[sd.Index] = path
+	}
+	for _, i := range indexes {
+		if i < 0 || i >= r.numFields {
+			return fmt.Errorf("Require: field %d doesn't exist", i)
+		}
+		r.required = append(r.required, requiredField{i, names[i]})
+	}
+	return nil
+}
+
+// checkRequired returns an error if any of the required fields weren't found.
+func (r *Resolver) checkRequired(p *Presence) error {
+	for _, rf := range r.required {
+		if !p.Found(rf.index) {
+			return &MissingFieldError{Field: rf.field, Void: p.Void(rf.index)}
+		}
+	}
+	return nil
+}
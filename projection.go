@@ -0,0 +1,136 @@
+package fastmsgpack
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ProjectedField copies the input field From to the output field To. Both use the syntax of NewResolver, but To can't contain array indexes.
+type ProjectedField struct {
+	From string
+	To   string
+}
+
+// Projection builds new msgpack maps out of fields of other msgpack, renaming and moving them as configured. See NewProjection.
+type Projection struct {
+	resolver *Resolver
+	root     *projectedMap
+}
+
+// projectedMap is a map in the output of a Projection.
+type projectedMap struct {
+	// keys are the msgpack encoded keys of the map, in the order they were first mentioned.
+	keys [][]byte
+	// values are field numbers of the resolver (ints) or nested maps (*projectedMap), one for each key.
+	values []any
+	// index maps the keys to their position in keys and values. It's only used while building.
+	index map[string]int
+}
+
+// NewProjection prepares a Projection that copies each From field to its To field, e.g. {From: "person.properties.firstName", To: "first_name"}.
+// The output is always a map. Nested output maps are created as needed, like "name.first" and "name.last" that both end up in the map under "name".
+// The same input field can be copied to multiple output fields, but each output field can only be given once and can't be inside another output field.
+// Wildcards and array resolvers aren't supported, as they result in multiple values per field.
+func NewProjection(fields []ProjectedField, opts ...DecodeOption) (*Projection, error) {
+	p := &Projection{root: &projectedMap{}}
+	var inputs []string
+	inputNumbers := map[string]int{}
+	for _, f := range fields {
+		n, ok := inputNumbers[f.From]
+		if !ok {
+			n = len(inputs)
+			inputNumbers[f.From] = n
+			inputs = append(inputs, f.From)
+		}
+		if err := p.root.add(f.To, n); err != nil {
+			return nil, err
+		}
+	}
+	r, err := NewResolver(inputs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if hasMultipleValues(r.interests) {
+		return nil, errors.New("NewProjection: wildcards aren't supported")
+	}
+	p.resolver = r
+	return p, nil
+}
+
+// add adds the output field to the map, creating nested maps as needed.
+func (m *projectedMap) add(field string, n int) error {
+	path, err := parsePath(field)
+	if err != nil || len(path) == 0 {
+		return errors.New("NewProjection: invalid output field: " + field)
+	}
+	for i, seg := range path {
+		if seg.isIndex {
+			return errors.New("NewProjection: output fields can't contain array indexes: " + field)
+		}
+		pos, ok := m.index[seg.key]
+		if !ok {
+			key, err := EncodeOptions{}.EncodeString(nil, seg.key)
+			if err != nil {
+				return err
+			}
+			if m.index == nil {
+				m.index = map[string]int{}
+			}
+			pos = len(m.keys)
+			m.index[seg.key] = pos
+			m.keys = append(m.keys, key)
+			if i == len(path)-1 {
+				m.values = append(m.values, n)
+				return nil
+			}
+			m.values = append(m.values, &projectedMap{})
+		}
+		child, ok := m.values[pos].(*projectedMap)
+		if !ok || i == len(path)-1 {
+			return errors.New("NewProjection: conflicting output fields: " + field)
+		}
+		m = child
+	}
+	return nil
+}
+
+// Project returns a new msgpack map with the fields of data, as configured in NewProjection.
+// Values are copied without decoding them. Fields that are missing or void in data are left out, as are nested maps that end up empty.
+// Flavor picks and injections are resolved and length-prefixed entries are kept like ResolveRaw does. The options are added to the options given to NewProjection.
+// The result is appended to dst and returned. dst can be nil.
+func (p *Projection) Project(dst, data []byte, opts ...DecodeOption) ([]byte, error) {
+	found, err := p.resolver.ResolveRaw(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	dst, _ = p.root.appendTo(dst, found)
+	return dst, nil
+}
+
+// appendTo appends the map with the found values and returns how many entries it has.
+func (m *projectedMap) appendTo(dst []byte, found [][]byte) ([]byte, int) {
+	dst = append(dst, 0xdf, 0, 0, 0, 0)
+	lengthOffset := len(dst) - 4
+	newLength := 0
+	for i, key := range m.keys {
+		switch v := m.values[i].(type) {
+		case int:
+			if found[v] == nil {
+				continue
+			}
+			dst = append(dst, key...)
+			dst = append(dst, found[v]...)
+		case *projectedMap:
+			start := len(dst)
+			var entries int
+			dst, entries = v.appendTo(append(dst, key...), found)
+			if entries == 0 {
+				dst = dst[:start]
+				continue
+			}
+		}
+		newLength++
+	}
+	binary.BigEndian.PutUint32(dst[lengthOffset:], uint32(newLength))
+	return dst, newLength
+}
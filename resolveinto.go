@@ -0,0 +1,142 @@
+package fastmsgpack
+
+import (
+	"sync"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+var decoderPool = sync.Pool{
+	New: func() any {
+		return &Decoder{nestingInfo: make([]nestingInfo, 0, 8)}
+	},
+}
+
+// TypedValue is a field found by ResolveTypedInto. Scalars are stored without boxing them in an interface.
+type TypedValue struct {
+	// Type is the type of the value, or TypeInvalid if the field wasn't found.
+	// Flavor picks, injections and length-prefixed entries are resolved, so Type is never TypeFlavorSelector, TypeInjection or TypeVoid.
+	Type ValueType
+	// Int is set for TypeInt, unless WithUint64 was given. Then the value is stored in Any like DecodeValue would return it.
+	Int int
+	// Float is set for TypeFloat32 and TypeFloat64.
+	Float float64
+	// Bool is set for TypeBool.
+	Bool bool
+	// Str is set for TypeString.
+	Str string
+	// Any is set for all other types, with the same value as Resolve would return. Wildcards and array resolvers result in a TypedValue with TypeArray and their []any or [][]any in Any.
+	Any any
+}
+
+// ResolveInto is like Resolve, but reuses dst for the result if it has enough capacity and uses a pooled Decoder.
+// Scalars are still boxed in an any, which allocates for most values. Use ResolveTypedInto to avoid that.
+func (r *Resolver) ResolveInto(dst []any, data []byte, opts ...DecodeOption) ([]any, error) {
+	dst = resize(dst, r.numFields)
+	d := r.pooledDecoder(data, opts)
+	defer releaseDecoder(d)
+	rc := resolveCall{
+		decoder: d,
+		result:  dst,
+	}
+	if err := r.run(&rc, data); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ResolveTypedInto is like ResolveInto, but stores the fields as TypedValues so scalars don't have to be boxed.
+// Fields registered with the typed Add methods (like AddInt) are not converted nor defaulted.
+// For documents with only scalar and string fields it doesn't allocate once dst is large enough.
+func (r *Resolver) ResolveTypedInto(dst []TypedValue, data []byte, opts ...DecodeOption) ([]TypedValue, error) {
+	dst = resize(dst, r.numFields)
+	d := r.pooledDecoder(data, opts)
+	defer releaseDecoder(d)
+	rc := resolveCall{
+		decoder:     d,
+		typedResult: dst,
+	}
+	if err := r.run(&rc, data); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// resize returns a cleared slice of length n, reusing s if it's large enough.
+func resize[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	s = s[:n]
+	clear(s)
+	return s
+}
+
+// pooledDecoder gets a Decoder from the pool and prepares it for data. It should be given to releaseDecoder when done.
+func (r *Resolver) pooledDecoder(data []byte, opts []DecodeOption) *Decoder {
+	d := decoderPool.Get().(*Decoder)
+	d.Reset(data)
+	d.opt = internal.DecodeOptions{}
+	for _, o := range r.decodeOptions {
+		o(&d.opt)
+	}
+	for _, o := range opts {
+		o(&d.opt)
+	}
+	return d
+}
+
+// releaseDecoder returns a Decoder to the pool, without retaining the data it was decoding.
+func releaseDecoder(d *Decoder) {
+	d.Reset(nil)
+	d.opt = internal.DecodeOptions{}
+	decoderPool.Put(d)
+}
+
+// resolveTyped stores the next value as field x.
+func (rc *resolveCall) resolveTyped(x int) error {
+	raw, err := rc.decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	opt := rc.decoder.nestedOptions()
+	opt.UnwrapLengthPrefix = true
+	b, err := unwrapRaw(raw, opt)
+	if err == nil {
+		err = decodeTyped(&rc.typedResult[x], b, opt)
+	}
+	if err != nil {
+		if err == ErrVoid {
+			rc.typedResult[x] = TypedValue{}
+			return err
+		}
+		offset, _ := internal.OffsetIn(rc.decoder.root, raw)
+		return decodeErrorAt(err, offset, "")
+	}
+	return nil
+}
+
+func decodeTyped(v *TypedValue, data []byte, opt internal.DecodeOptions) error {
+	var err error
+	v.Type = DecodeType(data)
+	switch v.Type {
+	case TypeNil:
+		return nil
+	case TypeBool:
+		v.Bool, _, err = internal.DecodeBool(data, opt)
+		return err
+	case TypeInt:
+		if !opt.Uint64 {
+			v.Int, _, err = internal.DecodeInt(data, opt)
+			return err
+		}
+	case TypeFloat32, TypeFloat64:
+		v.Float, _, err = internal.DecodeFloat64(data, opt)
+		return err
+	case TypeString:
+		v.Str, _, err = internal.DecodeString(data, opt)
+		return err
+	}
+	v.Any, _, err = decodeValue(data, opt)
+	return err
+}
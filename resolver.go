@@ -3,6 +3,7 @@ package fastmsgpack
 
 import (
 	"errors"
+	"strconv"
 	"strings"
 
 	"github.com/Jille/genericz/slicez"
@@ -17,16 +18,24 @@ func Decode(data []byte, opts ...DecodeOption) (any, error) {
 	for _, o := range opts {
 		o(&opt)
 	}
+	if err := opt.CheckBytes(data); err != nil {
+		return nil, decodeErrorAt(err, 0, "")
+	}
 	v, _, err := decodeValue(data, opt)
-	return v, err
+	return v, decodeErrorAt(err, 0, "")
 }
 
 // NewResolver prepares a new resolver. It can be reused for multiple Resolve calls.
-// You can't query the same field twice. You can't even query a child of something else you request (e.g. both "person.properties" and "person.properties.age"). This is the only reason NewResolver might return an error.
+// Fields are paths separated by dots. With WithNonStringKeys, integer map keys can be addressed by their decimal representation, e.g. "sparse.3".
+// Dots, brackets and backslashes in map keys must be escaped with a backslash, e.g. "domains.example\\.com". Describe returns the fields escaped the same way.
+// Array elements can be addressed by index, e.g. "person.addresses[0].street". Negative indexes count from the end, so "items[-1]" is the last item.
+// "items[*].id" returns a []any with the id of every item.
+// The empty field "" is the value itself and fields can start with an index (like "[0]"), which is mostly useful for subresolvers over arrays of scalars or arrays.
+// You can query a field and its children at the same time (e.g. both "person.properties" and "person.properties.age"); both are resolved in a single pass.
+// You can't query the same field twice. That and malformed fields are the only reasons NewResolver might return an error.
 // The dictionary is optional and can be nil.
 func NewResolver(fields []string, opts ...DecodeOption) (*Resolver, error) {
-	interests := map[string]any{}
-	r := &Resolver{interests, opts, len(fields)}
+	r := &Resolver{decodeOptions: opts, numFields: len(fields)}
 	for n, f := range fields {
 		if err := r.addField(f, n); err != nil {
 			return nil, err
@@ -35,22 +44,52 @@ func NewResolver(fields []string, opts ...DecodeOption) (*Resolver, error) {
 	return r, nil
 }
 
+// NewResolverFromPaths is like NewResolver, but takes fields that are already split into map keys. No escaping is needed, and keys like "[0]" are just keys.
+// An empty path refers to the value itself, like "" does for NewResolver.
+func NewResolverFromPaths(paths [][]string, opts ...DecodeOption) (*Resolver, error) {
+	r := &Resolver{decodeOptions: opts, numFields: len(paths)}
+	for n, p := range paths {
+		path := make([]pathSegment, len(p))
+		escaped := make([]string, len(p))
+		for i, k := range p {
+			path[i] = pathSegment{key: k}
+			escaped[i] = escapePathSegment(k)
+		}
+		if err := r.addPath(path, strings.Join(escaped, "."), n); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
 type subresolver struct {
-	interests   map[string]any
+	interests   any
 	destination int
 	numFields   int
+	// filter decides which elements to keep, based on their resolved fields. nil keeps all of them.
+	filter func(found []any) bool
+	// filterName is the name of the filter, if it was given one with AddNamedFilteredArrayResolver.
+	filterName string
 }
 
 type Resolver struct {
-	interests     map[string]any
+	// interests is a tree of map[string]any, *arrayInterests, *nestedInterests, subresolvers and field numbers (ints). nil means we want nothing, but still expect a map.
+	interests     any
 	decodeOptions []DecodeOption
 	numFields     int
+	typedFields   []typedField
+	required      []requiredField
+	// compiled is a copy of interests with the maps compiled against compiledDict by CompileForDict.
+	compiled     any
+	compiledDict *Dict
 }
 
 // AddArrayResolver allows resolving inside array fields. For example like this pseudocode: `r.AddArrayResolve("person.addresses", NewResolver(["street"]))`.
 // It returns the offset in the return value from Resolve(), which will be of type [][]any.
 // AddArrayResolver can not be called concurrently with itself or Resolve.
 // The dict that was given to the subresolver is not used.
+// Elements don't need to be maps: a subresolver with the field "" returns each element itself, and fields like "[0]" index into nested arrays.
+// Elements that don't have the shape the subresolver expects (like a string for a subresolver with map keys) result in nil for those fields.
 //
 //	r, err := NewResolver([]string{"person.properties.age"}, nil)
 //	sub, err := NewResolver([]string{"street", "number"}, nil)
@@ -59,72 +98,387 @@ type Resolver struct {
 //	age := found[0] // e.g. 5
 //	addresses := found[addrOffset] // e.g. [][]any{[]any{"Main Street", 1}, []any{"Second Street", 2}}
 func (r *Resolver) AddArrayResolver(field string, sub *Resolver) (int, error) {
+	return r.AddFilteredArrayResolver(field, sub, nil)
+}
+
+// AddFilteredArrayResolver is like AddArrayResolver, but only keeps the elements for which keep returns true. keep is called with the fields the subresolver found in the element.
+// Select drops the same elements.
+//
+//	sub, err := NewResolver([]string{"type", "street"})
+//	homeOffset, err := r.AddFilteredArrayResolver("person.addresses", sub, func(found []any) bool { return found[0] == "home" })
+func (r *Resolver) AddFilteredArrayResolver(field string, sub *Resolver, keep func(found []any) bool) (int, error) {
+	return r.addArrayResolver(field, sub, "", keep)
+}
+
+// AddNamedFilteredArrayResolver is like AddFilteredArrayResolver, but gives the filter a name so the Resolver can be described with Description.
+// NewResolverFromDescription looks the filter up by this name.
+func (r *Resolver) AddNamedFilteredArrayResolver(field string, sub *Resolver, name string, keep func(found []any) bool) (int, error) {
+	if name == "" {
+		return -1, errors.New("AddNamedFilteredArrayResolver: the filter needs a name")
+	}
+	return r.addArrayResolver(field, sub, name, keep)
+}
+
+func (r *Resolver) addArrayResolver(field string, sub *Resolver, filterName string, keep func(found []any) bool) (int, error) {
+	if len(sub.typedFields) > 0 || len(sub.required) > 0 {
+		return -1, errors.New("AddArrayResolver: subresolvers can't have typed or required fields")
+	}
 	dst := r.numFields
-	if err := r.addField(field, subresolver{sub.interests, dst, sub.numFields}); err != nil {
+	if err := r.addField(field, subresolver{sub.interests, dst, sub.numFields, keep, filterName}); err != nil {
 		return -1, err
 	}
 	r.numFields++
 	return dst, nil
 }
 
+// arrayInterests describes which elements of an array we want. Negative indexes count from the end.
+type arrayInterests struct {
+	indexes  map[int]any
+	wildcard *wildcardInterests
+}
+
+// wildcardInterests describes what we want from every element of an array.
+// The field numbers in interests are local to the wildcard. The results for each of them are gathered in a []any, which is stored at the corresponding destination.
+type wildcardInterests struct {
+	interests    any
+	destinations []int
+}
+
+// nestedInterests is used when we want a value (parent, a field number or subresolver) and also things inside it (children).
+type nestedInterests struct {
+	parent   any
+	children any
+}
+
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath splits a field like "person.addresses[0].street" into its segments.
+// A backslash escapes the next character, so keys containing dots can be addressed like "domains.example\\.com".
+// The empty string is the value itself, so it results in no segments.
+func parsePath(field string) ([]pathSegment, error) {
+	if field == "" {
+		return nil, nil
+	}
+	var ret []pathSegment
+	var key []byte
+	inKey := true
+	for i := 0; len(field) > i; i++ {
+		switch c := field[i]; c {
+		case '\\':
+			if !inKey || i+1 == len(field) {
+				return nil, errors.New("NewResolver: invalid escape in field: " + field)
+			}
+			i++
+			key = append(key, field[i])
+		case '.':
+			if inKey {
+				ret = append(ret, pathSegment{key: string(key)})
+				key = key[:0]
+			}
+			inKey = true
+		case '[':
+			if inKey && len(key) > 0 {
+				ret = append(ret, pathSegment{key: string(key)})
+				key = key[:0]
+			}
+			inKey = false
+			end := strings.IndexByte(field[i:], ']')
+			if end == -1 {
+				return nil, errors.New("NewResolver: unterminated array index in field: " + field)
+			}
+			idx := field[i+1 : i+end]
+			i += end
+			if idx == "*" {
+				ret = append(ret, pathSegment{isIndex: true, wildcard: true})
+				continue
+			}
+			n, err := strconv.Atoi(idx)
+			if err != nil {
+				return nil, errors.New("NewResolver: invalid array index in field: " + field)
+			}
+			ret = append(ret, pathSegment{isIndex: true, index: n})
+		default:
+			if !inKey {
+				return nil, errors.New("NewResolver: expected . or [ after array index in field: " + field)
+			}
+			key = append(key, c)
+		}
+	}
+	if inKey {
+		ret = append(ret, pathSegment{key: string(key)})
+	}
+	return ret, nil
+}
+
+// escapePathSegment escapes a map key so parsePath will see it as a single key.
+func escapePathSegment(key string) string {
+	if !strings.ContainsAny(key, ".[\\") {
+		return key
+	}
+	var sb strings.Builder
+	for i := 0; len(key) > i; i++ {
+		switch key[i] {
+		case '.', '[', '\\':
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(key[i])
+	}
+	return sb.String()
+}
+
 func (r *Resolver) addField(field string, what any) error {
-	sp := strings.Split(field, ".")
-	dst := r.interests
-	for len(sp) > 1 {
-		v := dst[sp[0]]
-		m, ok := v.(map[string]any)
+	path, err := parsePath(field)
+	if err != nil {
+		return err
+	}
+	return r.addPath(path, field, what)
+}
+
+func (r *Resolver) addPath(path []pathSegment, field string, what any) error {
+	if err := addInterest(&r.interests, path, what); err != nil {
+		return errors.New("NewResolver: conflicting fields requested: " + field)
+	}
+	r.compiled, r.compiledDict = nil, nil
+	return nil
+}
+
+var errConflictingFields = errors.New("conflicting fields")
+
+// addInterest adds $what (a field number or subresolver) at the given path below node.
+func addInterest(node *any, path []pathSegment, what any) error {
+	if len(path) == 0 {
+		switch (*node).(type) {
+		case nil:
+			*node = what
+		case map[string]any, *arrayInterests:
+			*node = &nestedInterests{parent: what, children: *node}
+		default:
+			return errConflictingFields
+		}
+		return nil
+	}
+	switch n := (*node).(type) {
+	case int, subresolver:
+		nested := &nestedInterests{parent: n}
+		*node = nested
+		return addInterest(&nested.children, path, what)
+	case *nestedInterests:
+		return addInterest(&n.children, path, what)
+	}
+	seg := path[0]
+	if !seg.isIndex {
+		m, ok := (*node).(map[string]any)
 		if !ok {
-			if v != nil {
-				return errors.New("NewResolver: conflicting fields requested")
+			if *node != nil {
+				return errConflictingFields
 			}
 			m = map[string]any{}
-			dst[sp[0]] = m
+			*node = m
 		}
-		dst = m
-		sp = sp[1:]
+		child := m[seg.key]
+		if err := addInterest(&child, path[1:], what); err != nil {
+			return err
+		}
+		m[seg.key] = child
+		return nil
 	}
-	if dst[sp[0]] != nil {
-		return errors.New("NewResolver: conflicting fields requested: " + field)
+	ai, ok := (*node).(*arrayInterests)
+	if !ok {
+		if *node != nil {
+			return errConflictingFields
+		}
+		ai = &arrayInterests{}
+		*node = ai
 	}
-	dst[sp[0]] = what
+	if seg.wildcard {
+		if ai.wildcard == nil {
+			ai.wildcard = &wildcardInterests{}
+		}
+		w := ai.wildcard
+		local := len(w.destinations)
+		var dst int
+		switch x := what.(type) {
+		case int:
+			dst = x
+			what = local
+		case subresolver:
+			dst = x.destination
+			x.destination = local
+			what = x
+		}
+		if err := addInterest(&w.interests, path[1:], what); err != nil {
+			return err
+		}
+		w.destinations = append(w.destinations, dst)
+		return nil
+	}
+	if ai.indexes == nil {
+		ai.indexes = map[int]any{}
+	}
+	child := ai.indexes[seg.index]
+	if err := addInterest(&child, path[1:], what); err != nil {
+		return err
+	}
+	ai.indexes[seg.index] = child
 	return nil
 }
 
+// lastIndex returns the highest position we're interested in for an array with the given number of elements, or -1 if there is none.
+func (ai *arrayInterests) lastIndex(elements int) int {
+	if ai.wildcard != nil {
+		return elements - 1
+	}
+	last := -1
+	for i := range ai.indexes {
+		if i < 0 {
+			i += elements
+		}
+		if i < elements && i > last {
+			last = i
+		}
+	}
+	return last
+}
+
+// elementInterest returns what we want from element i of an array with the given number of elements, or nil if we don't want it.
+// If multiple interests apply to the element, they are merged, which loses the field numbers.
+func (ai *arrayInterests) elementInterest(i, elements int) any {
+	var ret any
+	if ai.wildcard != nil {
+		ret = ai.wildcard.interests
+	}
+	if x, ok := ai.indexes[i]; ok {
+		ret = mergeInterests(ret, x)
+	}
+	if x, ok := ai.indexes[i-elements]; ok {
+		ret = mergeInterests(ret, x)
+	}
+	return ret
+}
+
+// mergeInterests combines what we want from a value. Field numbers are meaningless in the result, which is only good for selecting.
+func mergeInterests(a, b any) any {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			ret := make(map[string]any, len(a)+len(b))
+			for k, v := range a {
+				ret[k] = v
+			}
+			for k, v := range b {
+				ret[k] = mergeInterests(ret[k], v)
+			}
+			return ret
+		}
+	case *arrayInterests:
+		if b, ok := b.(*arrayInterests); ok {
+			ret := &arrayInterests{indexes: make(map[int]any, len(a.indexes)+len(b.indexes))}
+			for k, v := range a.indexes {
+				ret.indexes[k] = v
+			}
+			for k, v := range b.indexes {
+				ret.indexes[k] = mergeInterests(ret.indexes[k], v)
+			}
+			switch {
+			case a.wildcard == nil:
+				ret.wildcard = b.wildcard
+			case b.wildcard == nil:
+				ret.wildcard = a.wildcard
+			default:
+				ret.wildcard = &wildcardInterests{interests: mergeInterests(a.wildcard.interests, b.wildcard.interests)}
+			}
+			return ret
+		}
+	}
+	// Just take the whole value.
+	return 0
+}
+
 type SubresolverDescription struct {
 	Fields       []string
 	Subresolvers map[string]SubresolverDescription
 	Index        int
+	// Filter is the name of the filter given to AddNamedFilteredArrayResolver.
+	Filter string
+	// filtered is true if the subresolver has a filter, even if it has no name.
+	filtered bool
 }
 
 // Describe returns which fields and subresolvers were registered to this Resolver.
+// Subresolvers that were added before other fields leave an empty string at their index in the fields.
 // The returned values should not be modified.
 func (r *Resolver) Describe() ([]string, map[string]SubresolverDescription) {
 	fields := make([]string, r.numFields)
 	subs := map[string]SubresolverDescription{}
-	recurseInterests(fields, subs, r.interests, "")
-	fields = fields[:len(fields)-len(subs)]
-	return fields, subs
+	recurseInterests(fields, subs, r.interests, "", nil)
+	return trimSubresolvers(fields, subs), subs
 }
 
-func recurseInterests(fields []string, subs map[string]SubresolverDescription, i any, prefix string) {
+// trimSubresolvers cuts the indexes of the subresolvers from the end of fields.
+func trimSubresolvers(fields []string, subs map[string]SubresolverDescription) []string {
+	isSub := make(map[int]bool, len(subs))
+	for _, sd := range subs {
+		isSub[sd.Index] = true
+	}
+	for len(fields) > 0 && isSub[len(fields)-1] {
+		fields = fields[:len(fields)-1]
+	}
+	return fields
+}
+
+// recurseInterests fills in the fields and subresolvers. remap translates the field numbers inside wildcards to the field numbers of the Resolver. It is nil outside of wildcards.
+func recurseInterests(fields []string, subs map[string]SubresolverDescription, i any, prefix string, remap []int) {
+	dest := func(n int) int {
+		if remap == nil {
+			return n
+		}
+		return remap[n]
+	}
 	switch i := i.(type) {
 	case int:
-		fields[i] = prefix
+		fields[dest(i)] = prefix
 	case map[string]any:
 		if prefix != "" {
 			prefix += "."
 		}
 		for k, v := range i {
-			recurseInterests(fields, subs, v, prefix+k)
+			recurseInterests(fields, subs, v, prefix+escapePathSegment(k), remap)
+		}
+	case *arrayInterests:
+		for n, v := range i.indexes {
+			recurseInterests(fields, subs, v, prefix+internal.IndexSegment(n), remap)
+		}
+		if i.wildcard != nil {
+			wildcardRemap := make([]int, len(i.wildcard.destinations))
+			for n, d := range i.wildcard.destinations {
+				wildcardRemap[n] = dest(d)
+			}
+			recurseInterests(fields, subs, i.wildcard.interests, prefix+"[*]", wildcardRemap)
 		}
+	case *nestedInterests:
+		recurseInterests(fields, subs, i.parent, prefix, remap)
+		recurseInterests(fields, subs, i.children, prefix, remap)
 	case subresolver:
 		sd := SubresolverDescription{
-			Index:        i.destination,
+			Index:        dest(i.destination),
 			Fields:       make([]string, i.numFields),
 			Subresolvers: map[string]SubresolverDescription{},
+			Filter:       i.filterName,
+			filtered:     i.filter != nil,
 		}
-		recurseInterests(sd.Fields, sd.Subresolvers, i.interests, "")
-		sd.Fields = sd.Fields[:len(sd.Fields)-len(sd.Subresolvers)]
+		recurseInterests(sd.Fields, sd.Subresolvers, i.interests, "", nil)
+		sd.Fields = trimSubresolvers(sd.Fields, sd.Subresolvers)
 		subs[prefix] = sd
 	}
 }
@@ -136,18 +490,174 @@ func (r *Resolver) Resolve(data []byte, opts ...DecodeOption) (foundFields []any
 		decoder: NewDecoder(data, slicez.Concat(r.decodeOptions, opts)...),
 		result:  make([]any, r.numFields),
 	}
-	if err := rc.recurseMap(r.interests, false); err != nil {
+	if err := r.run(&rc, data); err != nil {
 		return nil, err
 	}
 	return rc.result, nil
 }
 
+// noInterests is used for Resolvers without fields, so they still require the data to be a map.
+var noInterests = map[string]any{}
+
+func (r *Resolver) rootInterests() any {
+	if r.interests == nil {
+		return noInterests
+	}
+	return r.interests
+}
+
+// run does the work for all Resolve variants. rc must have its decoder and one of the results set.
+func (r *Resolver) run(rc *resolveCall, data []byte) error {
+	if err := rc.decoder.opt.CheckBytes(data); err != nil {
+		return decodeErrorAt(err, 0, "")
+	}
+	if rc.presence == nil && len(r.required) > 0 {
+		rc.presence = newPresence(r.numFields)
+	}
+	if rc.result != nil {
+		r.markTypedFields(rc.result)
+	}
+	interests := r.rootInterests()
+	if r.compiledDict != nil && r.compiledDict.usedBy(rc.decoder.opt) {
+		interests = r.compiled
+	}
+	if err := rc.recurse(interests, false); err != nil {
+		return err
+	}
+	if err := r.checkRequired(rc.presence); err != nil {
+		return err
+	}
+	if rc.result != nil {
+		return r.convertTypedFields(rc.result)
+	}
+	return nil
+}
+
 type resolveCall struct {
 	decoder *Decoder
 	result  []any
+	// rawResult is set by ResolveRaw. The fields are stored here instead of in result.
+	rawResult [][]byte
+	// typedResult is set by ResolveTypedInto. The fields are stored here instead of in result.
+	typedResult []TypedValue
+	// presence is set if we need to track which fields were found.
+	presence *Presence
+}
+
+// store sets field x to a value that was gathered from multiple elements.
+func (rc *resolveCall) store(x int, v any) {
+	if rc.presence != nil {
+		rc.presence.mark(x, nil)
+	}
+	if rc.typedResult != nil {
+		rc.typedResult[x] = TypedValue{Type: TypeArray, Any: v}
+		return
+	}
+	rc.result[x] = v
+}
+
+// recurseElement is like recurse, but values that don't have the shape of the interests (like a string where we want map keys) are skipped as if they don't contain the fields.
+// It is used for array elements, which can have mixed content.
+func (rc *resolveCall) recurseElement(interest any, mustSkip bool) error {
+	if !shapeMatches(interest, rc.decoder.PeekType()) {
+		return rc.decoder.Skip()
+	}
+	return rc.recurse(interest, mustSkip)
+}
+
+// shapeMatches returns whether a value of type t can contain the interests.
+func shapeMatches(interest any, t ValueType) bool {
+	switch t {
+	case TypeFlavorSelector, TypeInjection, TypeVoid:
+		// We can't tell without resolving it, so let recurse find out.
+		return true
+	}
+	switch interest.(type) {
+	case map[string]any, *internedInterests:
+		return t == TypeMap
+	case *arrayInterests, subresolver:
+		return t == TypeArray
+	}
+	return true
 }
 
-func (rc *resolveCall) recurseMap(interests map[string]any, mustSkip bool) error {
+// resolveField stores the next value as field x.
+func (rc *resolveCall) resolveField(x int) error {
+	if rc.rawResult != nil {
+		return rc.resolveRaw(x)
+	}
+	if rc.typedResult != nil {
+		return rc.resolveTyped(x)
+	}
+	v, err := rc.decoder.DecodeValue()
+	if err != nil {
+		if err == ErrVoid {
+			if err := rc.decoder.Skip(); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	rc.result[x] = v
+	return nil
+}
+
+// recurse resolves the interests in the next value.
+// If the value is void, it is skipped and ErrVoid is returned. The same goes for the other recurse functions.
+func (rc *resolveCall) recurse(interest any, mustSkip bool) error {
+	switch x := interest.(type) {
+	case int:
+		err := rc.resolveField(x)
+		if rc.presence != nil {
+			rc.presence.mark(x, err)
+		}
+		return err
+	case map[string]any:
+		return rc.recurseMap(x, nil, mustSkip)
+	case *internedInterests:
+		return rc.recurseMap(x.keys, x.byIndex, mustSkip)
+	case subresolver:
+		return rc.recurseArray(x, mustSkip)
+	case *arrayInterests:
+		return rc.recurseIndexes(x, mustSkip)
+	case *nestedInterests:
+		return rc.recurseNested(x)
+	default:
+		return rc.decoder.Skip()
+	}
+}
+
+// subCall returns a resolveCall for a value we've already read with DecodeRaw, and the offset of that value to correct errors with.
+func (rc *resolveCall) subCall(raw []byte) (resolveCall, int) {
+	offset, _ := internal.OffsetIn(rc.decoder.root, raw)
+	return resolveCall{
+		decoder:     &Decoder{root: raw, data: raw, opt: rc.decoder.nestedOptions()},
+		result:      rc.result,
+		rawResult:   rc.rawResult,
+		typedResult: rc.typedResult,
+		presence:    rc.presence,
+	}, offset
+}
+
+// recurseNested resolves the value itself and then the things we want inside it.
+func (rc *resolveCall) recurseNested(n *nestedInterests) error {
+	raw, err := rc.decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	sub, offset := rc.subCall(raw)
+	if err := sub.recurse(n.parent, false); err != nil {
+		return decodeErrorAt(err, offset, "")
+	}
+	sub.decoder.Reset(raw)
+	if err := sub.recurseElement(n.children, false); err != nil && err != ErrVoid {
+		return decodeErrorAt(err, offset, "")
+	}
+	return nil
+}
+
+// recurseMap resolves the interests in the next map. If byIndex is set, keys interned in the dict of the decoder are looked up in it by their index instead of by their string (see CompileForDict).
+func (rc *resolveCall) recurseMap(interests map[string]any, byIndex []any, mustSkip bool) error {
 	elements, err := rc.decoder.DecodeMapLen()
 	if err != nil {
 		if err == ErrVoid {
@@ -161,37 +671,45 @@ func (rc *resolveCall) recurseMap(interests map[string]any, mustSkip bool) error
 	sought := len(interests)
 	for elements > 0 {
 		elements--
-		k, err := rc.decoder.DecodeString()
-		if err != nil {
-			if err == ErrVoid {
-				if err := rc.decoder.Skip(); err != nil {
-					return err
-				}
-				if err := rc.decoder.Skip(); err != nil {
-					return err
+		var k string
+		var x any
+		var n uint
+		var interned bool
+		if byIndex != nil {
+			n, interned = rc.decoder.decodeInternedKey()
+		}
+		if interned {
+			k = rc.decoder.opt.Dict.Strings[n]
+			if len(byIndex) > int(n) {
+				x = byIndex[n]
+			}
+		} else {
+			var ok bool
+			k, ok, err = rc.decoder.decodeKeyString()
+			if err != nil {
+				if err == ErrVoid {
+					if err := rc.decoder.Skip(); err != nil {
+						return err
+					}
+					if err := rc.decoder.Skip(); err != nil {
+						return err
+					}
+					continue
 				}
-				continue
+				return err
+			}
+			if ok {
+				x = interests[k]
 			}
-			return err
 		}
-		switch x := interests[k].(type) {
-		case int:
-			rc.result[x], err = rc.decoder.DecodeValue()
-			sought--
-		case map[string]any:
+		if x != nil {
 			sought--
-			err = rc.recurseMap(x, mustSkip || sought > 0)
-		case subresolver:
-			sought--
-			err = rc.recurseArray(x, mustSkip || sought > 0)
-		default:
+			err = rc.recurse(x, mustSkip || sought > 0)
+		} else {
 			err = rc.decoder.Skip()
 		}
-		if err == ErrVoid {
-			err = rc.decoder.Skip()
-		}
-		if err != nil {
-			return err
+		if err != nil && err != ErrVoid {
+			return decodeErrorAt(err, 0, k)
 		}
 		if elements == 0 {
 			break
@@ -206,6 +724,28 @@ func (rc *resolveCall) recurseMap(interests map[string]any, mustSkip bool) error
 	return nil
 }
 
+// decodeKeyString decodes a map key as a string. With WithNonStringKeys, integer keys are formatted in decimal, so they can be addressed in Resolver paths.
+// Other non-string keys can never be addressed by a path, so they are skipped and ok is false.
+func (d *Decoder) decodeKeyString() (k string, ok bool, err error) {
+	if d.opt.NonStringKeys {
+		switch d.PeekType() {
+		case TypeInt:
+			if u, err := d.DecodeUint(); err == nil {
+				return strconv.FormatUint(u, 10), true, nil
+			}
+			i, err := d.DecodeInt()
+			if err != nil {
+				return "", false, err
+			}
+			return strconv.Itoa(i), true, nil
+		case TypeNil, TypeBool, TypeFloat32, TypeFloat64, TypeBinary, TypeArray, TypeMap, TypeTimestamp, TypeUnknownExtension:
+			return "", false, d.Skip()
+		}
+	}
+	k, err = d.DecodeString()
+	return k, err == nil, err
+}
+
 func (rc *resolveCall) recurseArray(sub subresolver, mustSkip bool) error {
 	elements, err := rc.decoder.DecodeArrayLen()
 	if err != nil {
@@ -216,21 +756,142 @@ func (rc *resolveCall) recurseArray(sub subresolver, mustSkip bool) error {
 		}
 		return err
 	}
-	parentResults := rc.result
+	parentResults, typedResults, presence := rc.result, rc.typedResult, rc.presence
+	rc.typedResult, rc.presence = nil, nil
 	results := make([][]any, elements)
-	var voided int
+	var dropped int
+	var elem []any
 	for i := 0; elements > i; i++ {
-		rc.result = make([]any, sub.numFields)
-		if err := rc.recurseMap(sub.interests, mustSkip || i < elements-1); err != nil {
+		if elem == nil {
+			elem = make([]any, sub.numFields)
+		} else {
+			// Reuse the results of the previous element, which was dropped.
+			clear(elem)
+		}
+		rc.result = elem
+		if err := rc.recurseElement(sub.interests, mustSkip || i < elements-1); err != nil {
 			if err == ErrVoid {
-				voided++
+				dropped++
 				continue
 			}
-			return err
+			return decodeErrorAt(err, 0, internal.IndexSegment(i))
+		}
+		if sub.filter != nil && !sub.filter(elem) {
+			dropped++
+			continue
+		}
+		results[i-dropped] = elem
+		elem = nil
+	}
+	rc.result, rc.typedResult, rc.presence = parentResults, typedResults, presence
+	rc.store(sub.destination, results[:len(results)-dropped])
+	return nil
+}
+
+func (rc *resolveCall) recurseIndexes(ai *arrayInterests, mustSkip bool) error {
+	elements, err := rc.decoder.DecodeArrayLen()
+	if err != nil {
+		if err == ErrVoid {
+			if err := rc.decoder.Skip(); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	var wc *wildcardCall
+	if ai.wildcard != nil {
+		wc = &wildcardCall{
+			wildcardInterests: ai.wildcard,
+			results:           make([][]any, len(ai.wildcard.destinations)),
+			scratch:           make([]any, len(ai.wildcard.destinations)),
+		}
+		for i := range wc.results {
+			wc.results[i] = make([]any, 0, elements)
+		}
+	}
+	last := ai.lastIndex(elements)
+	for i := 0; last >= i; i++ {
+		if err := rc.resolveElement(ai, wc, i, elements, mustSkip || i < last); err != nil {
+			return decodeErrorAt(err, 0, internal.IndexSegment(i))
+		}
+	}
+	if wc != nil {
+		for i, dst := range ai.wildcard.destinations {
+			rc.store(dst, wc.results[i])
+		}
+	}
+	if mustSkip && last < elements-1 {
+		return rc.decoder.Break()
+	}
+	return nil
+}
+
+type wildcardCall struct {
+	*wildcardInterests
+	results [][]any
+	// scratch is reused as the result for every element.
+	scratch []any
+}
+
+// resolveElement resolves everything we want from element i of an array.
+func (rc *resolveCall) resolveElement(ai *arrayInterests, wc *wildcardCall, i, elements int, mustSkip bool) error {
+	var targets [2]any
+	n := 0
+	if x, ok := ai.indexes[i]; ok {
+		targets[n] = x
+		n++
+	}
+	if x, ok := ai.indexes[i-elements]; ok {
+		targets[n] = x
+		n++
+	}
+	if wc == nil && n <= 1 {
+		err := rc.recurseElement(targets[0], mustSkip)
+		if err == ErrVoid {
+			return nil
+		}
+		return err
+	}
+	if wc != nil && n == 0 {
+		err := rc.recurseWildcard(wc, mustSkip)
+		if err == ErrVoid {
+			return nil
 		}
-		results[i-voided] = rc.result
+		return err
+	}
+	// Multiple interests in the same element, so we need to go over it multiple times.
+	raw, err := rc.decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	sub, offset := rc.subCall(raw)
+	for _, t := range targets[:n] {
+		sub.decoder.Reset(raw)
+		if err := sub.recurseElement(t, false); err != nil && err != ErrVoid {
+			return decodeErrorAt(err, offset, "")
+		}
+	}
+	if wc != nil {
+		sub.decoder.Reset(raw)
+		if err := sub.recurseWildcard(wc, false); err != nil && err != ErrVoid {
+			return decodeErrorAt(err, offset, "")
+		}
+	}
+	return nil
+}
+
+// recurseWildcard resolves the wildcard interests in the next value and appends the results to wc.results.
+func (rc *resolveCall) recurseWildcard(wc *wildcardCall, mustSkip bool) error {
+	parentResults, typedResults, presence := rc.result, rc.typedResult, rc.presence
+	clear(wc.scratch)
+	rc.result, rc.typedResult, rc.presence = wc.scratch, nil, nil
+	err := rc.recurseElement(wc.interests, mustSkip)
+	rc.result, rc.typedResult, rc.presence = parentResults, typedResults, presence
+	if err != nil {
+		return err
+	}
+	for i, v := range wc.scratch {
+		wc.results[i] = append(wc.results[i], v)
 	}
-	rc.result = parentResults
-	rc.result[sub.destination] = results[:len(results)-voided]
 	return nil
 }
@@ -0,0 +1,114 @@
+package fastmsgpack
+
+import (
+	"errors"
+
+	"github.com/Jille/genericz/slicez"
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// ResolveRaw is like Resolve, but returns the msgpack data of each field instead of decoding it.
+// The returned slices point into the given data, except for fields that are injected with WithInjection. Fields that weren't found are nil.
+// Flavor picks and injections are resolved, both on the path to a field and for the field itself. Length-prefixed entries (extension 17) are returned as is, unless WithUnwrapLengthPrefix is given.
+// ResolveRaw doesn't support wildcards and array resolvers, as those result in multiple values per field.
+func (r *Resolver) ResolveRaw(data []byte, opts ...DecodeOption) ([][]byte, error) {
+	if hasMultipleValues(r.interests) {
+		return nil, errors.New("fastmsgpack: ResolveRaw doesn't support wildcards and array resolvers")
+	}
+	rc := resolveCall{
+		decoder:   NewDecoder(data, slicez.Concat(r.decodeOptions, opts)...),
+		rawResult: make([][]byte, r.numFields),
+	}
+	if err := r.run(&rc, data); err != nil {
+		return nil, err
+	}
+	return rc.rawResult, nil
+}
+
+// hasMultipleValues returns whether any of the interests result in a list of values.
+func hasMultipleValues(interests any) bool {
+	switch x := interests.(type) {
+	case map[string]any:
+		for _, v := range x {
+			if hasMultipleValues(v) {
+				return true
+			}
+		}
+	case *arrayInterests:
+		if x.wildcard != nil {
+			return true
+		}
+		for _, v := range x.indexes {
+			if hasMultipleValues(v) {
+				return true
+			}
+		}
+	case *nestedInterests:
+		return hasMultipleValues(x.parent) || hasMultipleValues(x.children)
+	case subresolver:
+		return true
+	}
+	return false
+}
+
+// resolveRaw stores the msgpack data of the next value as field x.
+func (rc *resolveCall) resolveRaw(x int) error {
+	raw, err := rc.decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	v, err := unwrapRaw(raw, rc.decoder.opt)
+	if err != nil {
+		offset, _ := internal.OffsetIn(rc.decoder.root, raw)
+		return decodeErrorAt(err, offset, "")
+	}
+	rc.rawResult[x] = v
+	return nil
+}
+
+// unwrapRaw resolves the flavor picks and injections at the start of raw, like the Decoder does transparently. Length-prefixed entries are only unwrapped if needed or requested.
+func unwrapRaw(raw []byte, opt internal.DecodeOptions) ([]byte, error) {
+	extType, data, err := internal.DecodeExtensionHeader(raw)
+	if err != nil {
+		if err == internal.ErrNotExtension {
+			return raw, nil
+		}
+		return nil, err
+	}
+	switch extType {
+	case 17: // Length-prefixed entry
+		inner, err := unwrapRaw(data, opt)
+		if err != nil {
+			return nil, err
+		}
+		if off, ok := internal.OffsetIn(data, inner); !opt.UnwrapLengthPrefix && ok && off == 0 && len(inner) == len(data) {
+			// Nothing inside needed resolving, so we can keep the wrapper.
+			return raw, nil
+		}
+		return inner, nil
+
+	case 18: // Flavor pick
+		j, err := internal.DecodeFlavorPick(data, opt)
+		if err != nil {
+			return nil, err
+		}
+		c, err := internal.ValueLength(data[j:])
+		if err != nil {
+			return nil, err
+		}
+		return unwrapRaw(data[j:j+c], opt)
+
+	case 19: // Void
+		return nil, ErrVoid
+
+	case 20: // Injection
+		b, err := internal.DecodeInjectionExtension(data, opt)
+		if err != nil {
+			return nil, err
+		}
+		return unwrapRaw(b, opt)
+
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,173 @@
+package fastmsgpack
+
+import (
+	"slices"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// SelectOptions changes how SelectWithOptions writes its output. The zero value gives the same result as Select.
+type SelectOptions struct {
+	// CompactHeaders writes every map and array header in the smallest form, rather than the 5 byte headers Select writes for the maps and arrays it builds.
+	CompactHeaders bool
+	// LengthPrefix adds a length-prefixed entry (extension 17) before every map and array, like LengthEncode.
+	LengthPrefix bool
+	// KeyEncoding re-encodes all string map keys with EncodeOptions.EncodeString, so keys in its Dict are interned and others are written as plain strings.
+	// Interned keys in the input are looked up in the dict given to the Resolver with WithDict. nil leaves the keys as they are.
+	KeyEncoding *EncodeOptions
+}
+
+// SelectWithOptions is like Select, but can write a smaller output, which is useful if it's going to be stored.
+// Values within flavors (extension 18) and injections (extension 20) are copied as is.
+// The result is appended to dst and returned. dst can be nil.
+func (r *Resolver) SelectWithOptions(dst, data []byte, so SelectOptions) ([]byte, error) {
+	if so == (SelectOptions{}) {
+		return r.Select(dst, data)
+	}
+	selected, err := r.Select(nil, data)
+	if err != nil {
+		return nil, err
+	}
+	if so.CompactHeaders || so.KeyEncoding != nil {
+		rw := selectRewriter{options: so}
+		for _, o := range r.decodeOptions {
+			o(&rw.opt)
+		}
+		rewritten, _, err := rw.rewrite(make([]byte, 0, len(selected)), selected)
+		if err != nil {
+			return nil, decodeErrorAt(err, 0, "")
+		}
+		selected = rewritten
+	}
+	if so.LengthPrefix {
+		return LengthEncode(dst, selected)
+	}
+	return append(dst, selected...), nil
+}
+
+type selectRewriter struct {
+	options SelectOptions
+	opt     internal.DecodeOptions
+}
+
+// rewrite appends the value at the start of data to dst with the headers and keys rewritten as configured. It returns the number of bytes of data consumed.
+func (rw *selectRewriter) rewrite(dst, data []byte) ([]byte, int, error) {
+	n, err := internal.ValueLength(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	value := data[:n]
+	if extType, payload, err := internal.DecodeExtensionHeader(value); err == nil {
+		if extType != 17 {
+			return append(dst, value...), n, nil
+		}
+		// Rewriting can change the size of the wrapped value, so we need a new length prefix.
+		if err := rw.opt.Descend(); err != nil {
+			return nil, 0, err
+		}
+		defer rw.opt.Ascend()
+		start := len(dst)
+		dst, _, err = rw.rewrite(dst, payload)
+		if err != nil {
+			return nil, 0, decodeErrorAt(err, len(value)-len(payload), "")
+		}
+		var h [6]byte
+		return slices.Insert(dst, start, appendLengthHeader(h[:0], len(dst)-start)...), n, nil
+	} else if err != internal.ErrNotExtension {
+		return nil, 0, err
+	}
+	if elements, header, ok := internal.DecodeUnwrappedMapLen(value); ok {
+		dst, err = rw.rewriteMap(dst, value, elements, header)
+		return dst, n, err
+	}
+	if elements, header, ok := internal.DecodeUnwrappedArrayLen(value); ok {
+		dst, err = rw.rewriteArray(dst, value, elements, header)
+		return dst, n, err
+	}
+	return append(dst, value...), n, nil
+}
+
+// rewriteMap is rewrite for maps. The map has the given number of elements, which start header bytes into value.
+func (rw *selectRewriter) rewriteMap(dst, value []byte, elements, header int) ([]byte, error) {
+	if err := rw.opt.CheckElements(elements, 2, len(value)-header); err != nil {
+		return nil, err
+	}
+	if err := rw.opt.Descend(); err != nil {
+		return nil, err
+	}
+	defer rw.opt.Ascend()
+	var err error
+	if rw.options.CompactHeaders {
+		if dst, err = internal.AppendMapLen(dst, elements); err != nil {
+			return nil, err
+		}
+	} else {
+		dst = append(dst, value[:header]...)
+	}
+	offset := header
+	for i := 0; elements > i; i++ {
+		keyLength, err := internal.ValueLength(value[offset:])
+		if err != nil {
+			return nil, decodeErrorAt(err, offset, "")
+		}
+		rawKey := value[offset : offset+keyLength]
+		if dst, err = rw.rewriteKey(dst, rawKey); err != nil {
+			return nil, decodeErrorAt(err, offset, "")
+		}
+		offset += keyLength
+		var n int
+		dst, n, err = rw.rewrite(dst, value[offset:])
+		if err != nil {
+			return nil, decodeErrorAt(err, offset, keySegment(rawKey, rw.opt))
+		}
+		offset += n
+	}
+	return dst, nil
+}
+
+// rewriteKey appends the map key, re-encoded with KeyEncoding if it's a string.
+func (rw *selectRewriter) rewriteKey(dst, rawKey []byte) ([]byte, error) {
+	if rw.options.KeyEncoding == nil {
+		return append(dst, rawKey...), nil
+	}
+	k, _, err := decodeMapKey(rawKey, rw.opt)
+	if err == ErrVoid {
+		return append(dst, rawKey...), nil
+	} else if err != nil {
+		return nil, err
+	}
+	s, ok := k.(string)
+	if !ok {
+		return append(dst, rawKey...), nil
+	}
+	return rw.options.KeyEncoding.EncodeString(dst, s)
+}
+
+// rewriteArray is rewrite for arrays. The array has the given number of elements, which start header bytes into value.
+func (rw *selectRewriter) rewriteArray(dst, value []byte, elements, header int) ([]byte, error) {
+	if err := rw.opt.CheckElements(elements, 1, len(value)-header); err != nil {
+		return nil, err
+	}
+	if err := rw.opt.Descend(); err != nil {
+		return nil, err
+	}
+	defer rw.opt.Ascend()
+	var err error
+	if rw.options.CompactHeaders {
+		if dst, err = internal.AppendArrayLen(dst, elements); err != nil {
+			return nil, err
+		}
+	} else {
+		dst = append(dst, value[:header]...)
+	}
+	offset := header
+	for i := 0; elements > i; i++ {
+		var n int
+		dst, n, err = rw.rewrite(dst, value[offset:])
+		if err != nil {
+			return nil, decodeErrorAt(err, offset, internal.IndexSegment(i))
+		}
+		offset += n
+	}
+	return dst, nil
+}
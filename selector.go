@@ -13,7 +13,7 @@ func (r *Resolver) Select(dst, data []byte) (_ []byte, retErr error) {
 		decoder:  NewDecoder(data, r.decodeOptions...),
 		selected: dst,
 	}
-	if err := sc.selectFromMap(r.interests, false, nil); err != nil {
+	if err := sc.selectValue(r.rootInterests(), false, nil); err != nil {
 		return nil, err
 	}
 	return sc.selected, nil
@@ -25,6 +25,46 @@ type selectCall struct {
 	selected []byte
 }
 
+// selectValue appends uncommitted and the parts of the next value we're interested in.
+// If the value is void, nothing is appended and ErrVoid is returned.
+func (sc *selectCall) selectValue(interest any, mustSkip bool, uncommitted []byte) error {
+	switch x := interest.(type) {
+	case nil:
+		if err := sc.decoder.Skip(); err != nil {
+			return err
+		}
+		sc.selected = append(sc.selected, uncommitted...)
+		sc.selected = append(sc.selected, 0xc0)
+		return nil
+	case map[string]any:
+		return sc.selectFromMap(x, mustSkip, uncommitted)
+	case subresolver:
+		return sc.selectFromArray(x, mustSkip, uncommitted)
+	case *arrayInterests:
+		return sc.selectFromIndexes(x, mustSkip, uncommitted)
+	default:
+		v, err := sc.decoder.DecodeRaw()
+		if err != nil {
+			return err
+		}
+		sc.selected = append(sc.selected, uncommitted...)
+		sc.selected = append(sc.selected, v...)
+		return nil
+	}
+}
+
+// selectElement is like selectValue for array elements. Elements that don't have the shape of the interests are replaced by nil, like resolveCall.recurseElement treats them as not having any of the fields.
+func (sc *selectCall) selectElement(interest any, mustSkip bool) error {
+	if !shapeMatches(interest, sc.decoder.PeekType()) {
+		if err := sc.decoder.Skip(); err != nil {
+			return err
+		}
+		sc.selected = append(sc.selected, 0xc0)
+		return nil
+	}
+	return sc.selectValue(interest, mustSkip, nil)
+}
+
 func (sc *selectCall) selectFromMap(interests map[string]any, mustSkip bool, uncommitted []byte) error {
 	elements, err := sc.decoder.DecodeMapLen()
 	if err != nil {
@@ -48,45 +88,23 @@ func (sc *selectCall) selectFromMap(interests map[string]any, mustSkip bool, unc
 		if err != nil {
 			return err
 		}
-		k, _, err := internal.DecodeString(rawKey, sc.decoder.opt)
+		kd := Decoder{root: rawKey, data: rawKey, opt: sc.decoder.opt}
+		k, matchable, err := kd.decodeKeyString()
 		if err != nil {
-			return err
+			offset, _ := internal.OffsetIn(sc.decoder.root, rawKey)
+			return decodeErrorAt(err, offset, "")
 		}
-		switch x := interests[k].(type) {
-		case int:
-			sought--
-			v, err := sc.decoder.DecodeRaw()
-			if err != nil {
-				if err == ErrVoid {
-					break
-				}
-				return err
-			}
-			sc.selected = append(sc.selected, rawKey...)
-			sc.selected = append(sc.selected, v...)
-			newLength++
-		case map[string]any:
+		if x, ok := interests[k]; matchable && ok {
 			sought--
-			if err := sc.selectFromMap(x, mustSkip || sought > 0, rawKey); err != nil {
-				if err == ErrVoid {
-					break
+			if err := sc.selectValue(x, mustSkip || sought > 0, rawKey); err != nil {
+				if err != ErrVoid {
+					return decodeErrorAt(err, 0, k)
 				}
-				return err
-			}
-			newLength++
-		case subresolver:
-			sought--
-			if err := sc.selectFromArray(x, mustSkip || sought > 0, rawKey); err != nil {
-				if err == ErrVoid {
-					break
-				}
-				return err
-			}
-			newLength++
-		default:
-			if err := sc.decoder.Skip(); err != nil {
-				return err
+			} else {
+				newLength++
 			}
+		} else if err := sc.decoder.Skip(); err != nil {
+			return decodeErrorAt(err, 0, k)
 		}
 		if elements == 0 {
 			break
@@ -118,15 +136,111 @@ func (sc *selectCall) selectFromArray(sub subresolver, mustSkip bool, uncommitte
 	sc.selected = append(sc.selected, 0xdd, 0, 0, 0, 0)
 	lengthOffset := len(sc.selected) - 4
 	newLength := 0
+	var found []any
+	if sub.filter != nil {
+		// The fields of an element are only needed to call the filter, so all elements can share them.
+		found = make([]any, sub.numFields)
+	}
 	for i := 0; elements > i; i++ {
-		if err := sc.selectFromMap(sub.interests, mustSkip || i < elements-1, nil); err != nil {
+		if sub.filter != nil {
+			kept, err := sc.selectFiltered(sub, found)
+			if err != nil {
+				if err == ErrVoid {
+					continue
+				}
+				return decodeErrorAt(err, 0, internal.IndexSegment(i))
+			}
+			if kept {
+				newLength++
+			}
+			continue
+		}
+		if err := sc.selectElement(sub.interests, mustSkip || i < elements-1); err != nil {
 			if err == ErrVoid {
 				continue
 			}
-			return err
+			return decodeErrorAt(err, 0, internal.IndexSegment(i))
 		}
 		newLength++
 	}
 	binary.BigEndian.PutUint32(sc.selected[lengthOffset:], uint32(newLength))
 	return nil
 }
+
+// selectFromIndexes selects the wanted elements from an array. Elements we're not interested in are replaced by nil, so the indexes in the result are the same as in the original.
+// If we only need elements from the start of the array, the rest is left out.
+func (sc *selectCall) selectFromIndexes(ai *arrayInterests, mustSkip bool, uncommitted []byte) error {
+	elements, err := sc.decoder.DecodeArrayLen()
+	if err != nil {
+		if err == ErrVoid {
+			if err := sc.decoder.Skip(); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	last := ai.lastIndex(elements)
+	newLength := last + 1
+	for i := range ai.indexes {
+		if i < 0 {
+			// Negative indexes only point at the same element if we keep all of them.
+			newLength = elements
+			last = elements - 1
+			break
+		}
+	}
+	sc.selected = append(sc.selected, uncommitted...)
+	sc.selected = append(sc.selected, 0xdd, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(sc.selected[len(sc.selected)-4:], uint32(newLength))
+	for i := 0; last >= i; i++ {
+		interest := ai.elementInterest(i, elements)
+		if interest == nil {
+			if err := sc.decoder.Skip(); err != nil {
+				return decodeErrorAt(err, 0, internal.IndexSegment(i))
+			}
+			sc.selected = append(sc.selected, 0xc0)
+			continue
+		}
+		if err := sc.selectElement(interest, mustSkip || i < last); err != nil {
+			if err != ErrVoid {
+				return decodeErrorAt(err, 0, internal.IndexSegment(i))
+			}
+			// Keep the element void, so the indexes of the elements after it don't change.
+			sc.selected = append(sc.selected, canonicalVoidExtension...)
+		}
+	}
+	if mustSkip && last < elements-1 {
+		return sc.decoder.Break()
+	}
+	return nil
+}
+
+// selectFiltered selects the next element of an array if the filter of the subresolver keeps it. found is used to resolve the fields of the element for the filter.
+func (sc *selectCall) selectFiltered(sub subresolver, found []any) (bool, error) {
+	// The element might be in injected or flavored data, so take its bytes before resolving rather than slicing sc.decoder.data afterwards.
+	raw, err := sc.decoder.DecodeRaw()
+	if err != nil {
+		return false, err
+	}
+	offset, _ := internal.OffsetIn(sc.decoder.root, raw)
+	clear(found)
+	rc := resolveCall{
+		decoder: &Decoder{root: raw, data: raw, opt: sc.decoder.nestedOptions()},
+		result:  found,
+	}
+	if err := rc.recurseElement(sub.interests, false); err != nil {
+		return false, decodeErrorAt(err, offset, "")
+	}
+	if !sub.filter(found) {
+		return false, nil
+	}
+	elem := selectCall{
+		decoder:  &Decoder{root: raw, data: raw, opt: sc.decoder.nestedOptions()},
+		selected: sc.selected,
+	}
+	if err := elem.selectElement(sub.interests, false); err != nil {
+		return false, decodeErrorAt(err, offset, "")
+	}
+	sc.selected = elem.selected
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package fastmsgpack
+
+import (
+	"io"
+	"slices"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// StreamDecoder reads concatenated msgpack values from an io.Reader.
+// It only buffers as much as it needs to frame the next value.
+type StreamDecoder struct {
+	r     io.Reader
+	buf   []byte
+	start int
+	end   int
+	err   error
+	dec   *Decoder
+	// skip is how far the value at start has been framed, relative to start, so reads don't have to re-parse it from the beginning.
+	skip internal.SkipProgress
+}
+
+// NewStreamDecoder creates a StreamDecoder that reads from r. The options are used for the Decoders returned by Next.
+func NewStreamDecoder(r io.Reader, opts ...DecodeOption) *StreamDecoder {
+	return &StreamDecoder{
+		r:   r,
+		dec: NewDecoder(nil, opts...),
+	}
+}
+
+// NextRaw returns the msgpack data of the next value in the stream.
+// The returned data is only valid until the next call to NextRaw or Next.
+// It returns io.EOF if the stream ended cleanly and io.ErrUnexpectedEOF if it ended halfway through a value.
+func (s *StreamDecoder) NextRaw() ([]byte, error) {
+	for {
+		if s.end > s.start {
+			if s.skip.Pending == 0 {
+				s.skip = internal.SkipProgress{Pending: 1}
+			}
+			err := s.skip.Skip(s.buf[s.start:s.end])
+			if err == nil {
+				n := s.skip.Offset
+				s.skip = internal.SkipProgress{}
+				b := s.buf[s.start : s.start+n : s.start+n]
+				if err := s.dec.opt.CheckBytes(b); err != nil {
+					return nil, err
+				}
+				s.start += n
+				return b, nil
+			}
+			if err != internal.ErrShortInput {
+				return nil, err
+			}
+			if err := s.dec.opt.CheckBytes(s.buf[s.start:s.end]); err != nil {
+				return nil, err
+			}
+		}
+		if s.err != nil {
+			if s.err == io.EOF && s.end > s.start {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, s.err
+		}
+		s.fill()
+	}
+}
+
+// Next returns a Decoder for the next value in the stream.
+// The returned Decoder is reused by later calls, so it and any strings and []byte decoded from it are only valid until the next call to NextRaw or Next.
+// It returns io.EOF if the stream ended cleanly and io.ErrUnexpectedEOF if it ended halfway through a value.
+func (s *StreamDecoder) Next() (*Decoder, error) {
+	b, err := s.NextRaw()
+	if err != nil {
+		return nil, err
+	}
+	s.dec.Reset(b)
+	return s.dec, nil
+}
+
+func (s *StreamDecoder) fill() {
+	if s.start > 0 {
+		// Anything before s.start has been handed out before, and is no longer guaranteed to be valid.
+		s.end = copy(s.buf, s.buf[s.start:s.end])
+		s.start = 0
+	}
+	if s.end == len(s.buf) {
+		grow := max(len(s.buf), 4096)
+		if limit := s.dec.opt.Limits.MaxBytes; limit > 0 {
+			// One byte more than the limit is enough to tell that the pending value exceeds it.
+			grow = min(grow, limit+1-len(s.buf))
+		}
+		s.buf = slices.Grow(s.buf, grow)
+		s.buf = s.buf[:len(s.buf)+grow]
+	}
+	n, err := s.r.Read(s.buf[s.end:])
+	s.end += n
+	s.err = err
+}
@@ -0,0 +1,267 @@
+package fastmsgpack
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	// tagged is set if the name came from the msgpack tag.
+	tagged bool
+	encode reflectEncoder
+}
+
+type structInfo struct {
+	fields  []structField
+	byName  map[string]int
+	asArray bool
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+// getStructInfo returns the (cached) list of msgpack fields of the given struct type.
+// A struct can have a field like _msgpack struct{} with tag msgpack:",as_array" to be encoded as an array of its field values rather than a map.
+func getStructInfo(t reflect.Type) *structInfo {
+	if si, ok := structInfoCache.Load(t); ok {
+		return si.(*structInfo)
+	}
+	si := &structInfo{
+		fields: dominantFields(collectStructFields(t, nil, map[reflect.Type]bool{})),
+	}
+	if sf, ok := t.FieldByName("_msgpack"); ok && len(sf.Index) == 1 {
+		_, opts := parseTag(sf.Tag.Get("msgpack"))
+		si.asArray = slices.Contains(opts, "as_array")
+	}
+	si.byName = make(map[string]int, len(si.fields))
+	for i, f := range si.fields {
+		si.byName[f.name] = i
+		si.fields[i].encode = encoderForType(t.FieldByIndex(f.index).Type)
+	}
+	actual, _ := structInfoCache.LoadOrStore(t, si)
+	return actual.(*structInfo)
+}
+
+func parseTag(tag string) (name string, opts []string) {
+	name, rest, _ := strings.Cut(tag, ",")
+	if rest != "" {
+		opts = strings.Split(rest, ",")
+	}
+	return name, opts
+}
+
+func collectStructFields(t reflect.Type, index []int, visited map[reflect.Type]bool) []structField {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var ret []structField
+	for i := 0; t.NumField() > i; i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("msgpack")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		fieldIndex := append(append([]int{}, index...), i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if !sf.IsExported() && sf.Type.Kind() == reflect.Pointer {
+					// We can't allocate an unexported embedded pointer.
+					continue
+				}
+				ret = append(ret, collectStructFields(ft, fieldIndex, visited)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := structField{
+			name:   name,
+			index:  fieldIndex,
+			tagged: name != "",
+		}
+		if name == "" {
+			f.name = sf.Name
+		}
+		for _, o := range opts {
+			switch o {
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// dominantFields applies Go's rules for embedded fields with internal.DominantFields. The depth of a field is the length of its index.
+func dominantFields(fields []structField) []structField {
+	return internal.DominantFields(fields, func(f structField) (string, int, bool) {
+		return f.name, len(f.index), f.tagged
+	})
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex, but allocates nil embedded pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexNoAlloc is like reflect.Value.FieldByIndex, but returns false if it encounters a nil embedded pointer.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+type reflectEncoder func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error)
+
+var (
+	appenderType  = reflect.TypeOf((*interface{ AppendMsgpack([]byte) ([]byte, error) })(nil)).Elem()
+	marshalerType = reflect.TypeOf((*interface{ MarshalMsgpack() ([]byte, error) })(nil)).Elem()
+)
+
+// encoderForType picks how to encode values of type t. Types Encode has special handling for go through Encode, the rest avoids the allocation of reflect.Value.Interface().
+func encoderForType(t reflect.Type) reflectEncoder {
+	if t.Implements(appenderType) || t.Implements(marshalerType) || t == timeType || t == extensionType {
+		return encodeInterface
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return o.EncodeBool(dst, v.Bool()), nil
+		}
+	case reflect.String:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return o.EncodeString(dst, v.String())
+		}
+	case reflect.Int:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return appendCompactInt(dst, int(v.Int())), nil
+		}
+	case reflect.Uint:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return appendCompactUint(dst, uint(v.Uint())), nil
+		}
+	case reflect.Float32:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return o.EncodeFloat32(dst, float32(v.Float())), nil
+		}
+	case reflect.Float64:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return o.EncodeFloat64(dst, v.Float()), nil
+		}
+	case reflect.Struct:
+		return func(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+			return o.encodeStruct(dst, v)
+		}
+	default:
+		return encodeInterface
+	}
+}
+
+func encodeInterface(o EncodeOptions, dst []byte, v reflect.Value) ([]byte, error) {
+	if !v.CanInterface() {
+		// Fields promoted through an unexported embedded struct can be read, but not turned into an interface.
+		return o.encodeReflect(dst, v)
+	}
+	return o.Encode(dst, v.Interface())
+}
+
+func (o EncodeOptions) encodeStruct(dst []byte, rv reflect.Value) ([]byte, error) {
+	si := getStructInfo(rv.Type())
+	var err error
+	if si.asArray {
+		dst, err = internal.AppendArrayLen(dst, len(si.fields))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range si.fields {
+			fv, ok := fieldByIndexNoAlloc(rv, f.index)
+			if !ok {
+				dst = o.EncodeNil(dst)
+				continue
+			}
+			dst, err = f.encode(o, dst, fv)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dst, nil
+	}
+	dst, hdr := o.AppendPreliminaryMapLen(dst, len(si.fields))
+	var n int
+	for _, f := range si.fields {
+		fv, ok := fieldByIndexNoAlloc(rv, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		dst, err = o.EncodeString(dst, f.name)
+		if err != nil {
+			return nil, err
+		}
+		dst, err = f.encode(o, dst, fv)
+		if err != nil {
+			return nil, err
+		}
+		n++
+	}
+	if err := hdr.Finalize(dst, n); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// isEmptyValue decides whether an omitempty field is left out: false, 0, nil, empty strings, slices and maps and anything with an IsZero() method returning true (like time.Time).
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	if !v.CanInterface() {
+		return false
+	}
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		return z.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package msgpack_test
+
+import (
+	"testing"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderTypedMethods(t *testing.T) {
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, []byte{0xc4, 2, 'h', 'i'})
+	fb.SetElse([]byte{0xc0})
+
+	data, err := fastmsgpack.Encode(nil, []any{
+		[]byte{1, 2, 3},
+		"str",
+		nil,
+		uint64(1) << 63,
+		fastmsgpack.Extension{Type: 5, Data: []byte{9, 8}},
+		fastmsgpack.Extension{Type: 20, Data: []byte{1}},
+		fb,
+		fastmsgpack.Extension{Type: 19},
+		fastmsgpack.Extension{Type: 20, Data: []byte{2}},
+	})
+	require.NoError(t, err)
+	data, err = fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+
+	injected, err := fastmsgpack.Encode(nil, fastmsgpack.Extension{Type: 7, Data: []byte{1}})
+	require.NoError(t, err)
+	d := fastmsgpack.NewDecoder(data, fastmsgpack.WithFlavorSelector(1, 1), fastmsgpack.WithInjection(1, injected), fastmsgpack.WithInjection(2, []byte{0xc0}))
+	n, err := d.DecodeArrayLen()
+	require.NoError(t, err)
+	require.Equal(t, 9, n)
+
+	b, err := d.DecodeBytes()
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, b)
+
+	b, err = d.DecodeBytes()
+	require.NoError(t, err)
+	require.Equal(t, []byte("str"), b)
+
+	require.NoError(t, d.DecodeNil())
+	require.Error(t, d.DecodeNil())
+
+	u, err := d.DecodeUint()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1)<<63, u)
+
+	e, err := d.DecodeExtension()
+	require.NoError(t, err)
+	require.Equal(t, fastmsgpack.Extension{Type: 5, Data: []byte{9, 8}}, e)
+
+	e, err = d.DecodeExtension()
+	require.NoError(t, err)
+	require.Equal(t, fastmsgpack.Extension{Type: 7, Data: []byte{1}}, e)
+
+	b, err = d.DecodeBytes()
+	require.NoError(t, err)
+	require.Equal(t, []byte("hi"), b)
+
+	_, err = d.DecodeBytes()
+	require.ErrorIs(t, err, fastmsgpack.ErrVoid)
+	require.ErrorIs(t, d.DecodeNil(), fastmsgpack.ErrVoid)
+	require.NoError(t, d.Skip())
+
+	require.NoError(t, d.DecodeNil())
+}
@@ -0,0 +1,147 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+type encodeLevel int8
+
+type encodeInner struct {
+	Shared string `msgpack:"shared"`
+	Deep   int    `msgpack:"deep"`
+}
+
+type encodeBase struct {
+	encodeInner
+	ID     uint32 `msgpack:"id"`
+	Shared string `msgpack:"shared"`
+}
+
+type encodeTaggedLabel struct {
+	Label string `msgpack:"Label"`
+}
+
+type encodeUntaggedLabel struct {
+	Label string
+	Other string
+}
+
+type encodeConflict struct {
+	encodeTaggedLabel
+	encodeUntaggedLabel
+}
+
+type encodePoint struct {
+	_msgpack struct{} `msgpack:",as_array"`
+	X, Y     int
+	Label    string
+}
+
+type encodeStruct struct {
+	encodeBase
+	Name     string            `msgpack:"name"`
+	Nick     string            `msgpack:"nick,omitempty"`
+	Level    encodeLevel       `msgpack:"level"`
+	Tags     []string          `msgpack:"tags,omitempty"`
+	Attrs    map[string]string `msgpack:"attrs,omitempty"`
+	Parent   *encodeStruct     `msgpack:"parent,omitempty"`
+	When     time.Time         `msgpack:"when,omitempty"`
+	Ignored  string            `msgpack:"-"`
+	Point    encodePoint       `msgpack:"point"`
+	Points   []*encodePoint    `msgpack:"points"`
+	Any      any               `msgpack:"any"`
+	Untagged bool
+}
+
+func TestEncodeStruct(t *testing.T) {
+	in := encodeStruct{
+		encodeBase: encodeBase{
+			encodeInner: encodeInner{Shared: "shadowed", Deep: 3},
+			ID:          17,
+			Shared:      "base",
+		},
+		Name:    "Jan",
+		Level:   -2,
+		Parent:  &encodeStruct{Name: "Piet", Points: []*encodePoint{}},
+		Ignored: "nope",
+		Point:   encodePoint{X: 1, Y: 2, Label: "p"},
+		Points:  []*encodePoint{{X: 3}, nil},
+		Any:     encodePoint{X: 4},
+	}
+	data, err := fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+
+	decoded, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"deep":   3,
+		"id":     17,
+		"shared": "base",
+		"name":   "Jan",
+		"level":  -2,
+		"parent": map[string]any{
+			"deep":     0,
+			"id":       0,
+			"shared":   "",
+			"name":     "Piet",
+			"level":    0,
+			"point":    []any{0, 0, ""},
+			"points":   []any{},
+			"any":      nil,
+			"Untagged": false,
+		},
+		"point":    []any{1, 2, "p"},
+		"points":   []any{[]any{3, 0, ""}, nil},
+		"any":      []any{4, 0, ""},
+		"Untagged": false,
+	}, decoded)
+
+	var out encodeStruct
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out))
+	in.encodeInner.Shared = ""
+	in.Ignored = ""
+	in.Any = []any{4, 0, ""}
+	require.Equal(t, in, out)
+}
+
+func TestEncodeStructOmitEmpty(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, &encodeStruct{
+		Nick:  "J",
+		Tags:  []string{"a"},
+		Attrs: map[string]string{"k": "v"},
+		When:  time.Unix(1700000000, 0),
+	})
+	require.NoError(t, err)
+	decoded, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	m := decoded.(map[string]any)
+	require.Equal(t, "J", m["nick"])
+	require.Equal(t, []any{"a"}, m["tags"])
+	require.Equal(t, map[string]any{"k": "v"}, m["attrs"])
+	require.Equal(t, time.Unix(1700000000, 0), m["when"])
+	require.NotContains(t, m, "parent")
+}
+
+func TestEncodeNamedTypes(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, []encodeLevel{1, -1})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x92, 0xd0, 0x01, 0xd0, 0xff}, data)
+}
+
+func TestEncodeStructTaggedFieldWins(t *testing.T) {
+	// Like encoding/json, the tagged Label wins over the untagged one at the same depth.
+	in := encodeConflict{encodeTaggedLabel{"tagged"}, encodeUntaggedLabel{"untagged", "other"}}
+	data, err := fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+	decoded, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"Label": "tagged", "Other": "other"}, decoded)
+
+	var out encodeConflict
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out))
+	require.Equal(t, encodeConflict{encodeTaggedLabel{"tagged"}, encodeUntaggedLabel{"", "other"}}, out)
+}
@@ -0,0 +1,94 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEncoderDocument(t *testing.T, e *fastmsgpack.Encoder) map[string]any {
+	t.Helper()
+	long := strings.Repeat("x", 1000)
+	h, err := e.PreliminaryMapLen(1000)
+	require.NoError(t, err)
+	want := map[string]any{}
+	require.NoError(t, e.EncodeString("items"))
+	want["items"] = []any{}
+	ah, err := e.PreliminaryArrayLen(200)
+	require.NoError(t, err)
+	for i := 0; 100 > i; i++ {
+		require.NoError(t, e.Encode(long))
+		want["items"] = append(want["items"].([]any), long)
+	}
+	require.NoError(t, ah.Finalize(100))
+	require.NoError(t, e.EncodeString("name"))
+	require.NoError(t, e.Encode("interned"))
+	want["name"] = "interned"
+	require.NoError(t, e.EncodeString("nested"))
+	require.NoError(t, e.EncodeArrayLen(2))
+	require.NoError(t, e.Encode(1))
+	require.NoError(t, e.EncodeRaw([]byte{0xc0}))
+	want["nested"] = []any{1, nil}
+	require.NoError(t, h.Finalize(3))
+	require.NoError(t, e.Flush())
+	return want
+}
+
+func TestEncoder(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"interned"})
+	opts := fastmsgpack.EncodeOptions{Dict: map[string]int{"interned": 0}}
+
+	var buf bytes.Buffer
+	want := writeEncoderDocument(t, fastmsgpack.NewEncoder(&buf, opts))
+	got, err := fastmsgpack.Decode(buf.Bytes(), fastmsgpack.WithDict(dict))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	f, err := os.CreateTemp(t.TempDir(), "encoder")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write([]byte("prefix"))
+	require.NoError(t, err)
+	want = writeEncoderDocument(t, fastmsgpack.NewEncoder(f, opts))
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "prefix", string(data[:6]))
+	got, err = fastmsgpack.Decode(data[6:], fastmsgpack.WithDict(dict))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestEncoderFlushPending(t *testing.T) {
+	var buf bytes.Buffer
+	e := fastmsgpack.NewEncoder(&buf, fastmsgpack.EncodeOptions{})
+	h, err := e.PreliminaryArrayLen(10)
+	require.NoError(t, err)
+	require.NoError(t, e.Encode(1))
+	require.Error(t, e.Flush())
+	require.NoError(t, h.Finalize(1))
+	require.NoError(t, e.Flush())
+	require.Equal(t, []byte{0x91, 0x01}, buf.Bytes())
+}
+
+func TestEncoderFinalizeTwice(t *testing.T) {
+	var buf bytes.Buffer
+	e := fastmsgpack.NewEncoder(&buf, fastmsgpack.EncodeOptions{})
+	h1, err := e.PreliminaryArrayLen(10)
+	require.NoError(t, err)
+	h2, err := e.PreliminaryArrayLen(10)
+	require.NoError(t, err)
+	require.NoError(t, h1.Finalize(1))
+	require.Error(t, h1.Finalize(1))
+	// h2 is still pending, so flushing must fail.
+	require.Error(t, e.Flush())
+	require.NoError(t, h2.Finalize(0))
+	require.NoError(t, e.Flush())
+	require.Equal(t, []byte{0x91, 0x90}, buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/hexon/fastmsgpack/mpmerge"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptDocument returns a document with an invalid byte at person.addresses[2].street, and the offset of that byte.
+func corruptDocument(t *testing.T) ([]byte, int) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name": "Jan",
+			"addresses": []any{
+				map[string]any{"street": "Main"},
+				map[string]any{"street": "Side"},
+				map[string]any{"street": "XXXX"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	offset := bytes.Index(data, []byte("\xa4XXXX"))
+	require.NotEqual(t, -1, offset)
+	data[offset] = 0xc1
+	return data, offset
+}
+
+func requireDecodeError(t *testing.T, err error, offset int, path string) {
+	t.Helper()
+	var de *fastmsgpack.DecodeError
+	require.ErrorAs(t, err, &de)
+	require.Equal(t, offset, de.Offset, err.Error())
+	require.Equal(t, path, de.Path, err.Error())
+}
+
+func TestDecodeError(t *testing.T) {
+	data, offset := corruptDocument(t)
+
+	_, err := fastmsgpack.Decode(data)
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	_, err = fastmsgpack.NewDecoder(data).DecodeValue()
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	_, err = fastmsgpack.Canonical(nil, data, fastmsgpack.EncodeOptions{})
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	_, err = fastmsgpack.LengthEncode(nil, data)
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	err = fastmsgpack.Validate(data)
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+}
+
+func TestDecodeErrorResolver(t *testing.T) {
+	data, offset := corruptDocument(t)
+
+	r, err := fastmsgpack.NewResolver([]string{"person.addresses"})
+	require.NoError(t, err)
+	_, err = r.Resolve(data)
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	// Select doesn't decode the value, so it only notices the addresses array is broken.
+	_, err = r.Select(nil, data)
+	var de *fastmsgpack.DecodeError
+	require.ErrorAs(t, err, &de)
+	require.Equal(t, "person.addresses", de.Path)
+	require.Less(t, de.Offset, offset)
+}
+
+func TestDecodeErrorLengthEncoded(t *testing.T) {
+	data, _ := corruptDocument(t)
+	data[bytes.IndexByte(data, 0xc1)] = 0xa4
+	lengthEncoded, err := fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+	offset := bytes.Index(lengthEncoded, []byte("\xa4XXXX"))
+	lengthEncoded[offset] = 0xc1
+
+	_, err = fastmsgpack.Decode(lengthEncoded)
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+
+	_, err = fastmsgpack.Canonical(nil, lengthEncoded, fastmsgpack.EncodeOptions{})
+	requireDecodeError(t, err, offset, "person.addresses[2].street")
+}
+
+func TestDecodeErrorMerge(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"addresses": []any{
+				map[string]any{"street": "Main"},
+				map[string]any{"numbers!": 5},
+			},
+		},
+	})
+	require.NoError(t, err)
+	// Replace the key by an integer of the same size, which StringMap can't handle.
+	offset := bytes.Index(data, []byte("\xa8numbers!"))
+	copy(data[offset:], []byte{0xcf, 0, 0, 0, 0, 0, 0, 0, 1})
+
+	_, err = mpmerge.Merge(nil, data, fastmsgpack.EncodeOptions{}, fastmsgpack.MakeDict(nil), mpmerge.StringMap{Changes: map[string]mpmerge.Merger{
+		"person": mpmerge.StringMap{Changes: map[string]mpmerge.Merger{
+			"addresses": mpmerge.Each{Change: mpmerge.StringMap{Changes: map[string]mpmerge.Merger{
+				"number": mpmerge.Value{Value: 1},
+			}}},
+		}},
+	}})
+	requireDecodeError(t, err, offset, "person.addresses[1]")
+}
+
+func TestDecodeErrorVoid(t *testing.T) {
+	void, err := fastmsgpack.Extension{Type: 19}.AppendMsgpack(nil)
+	require.NoError(t, err)
+	_, err = fastmsgpack.Decode(void)
+	require.ErrorIs(t, err, fastmsgpack.ErrVoid)
+
+	_, err = fastmsgpack.NewDecoder(void).DecodeString()
+	require.ErrorIs(t, err, fastmsgpack.ErrVoid)
+
+	data, err := fastmsgpack.Encode(nil, []any{1, 2, fastmsgpack.Extension{Type: -1, Data: []byte{1, 2, 3}}})
+	require.NoError(t, err)
+	_, err = fastmsgpack.Decode(data)
+	requireDecodeError(t, err, 3, "[2]")
+	require.NotErrorIs(t, err, fastmsgpack.ErrVoid)
+}
@@ -0,0 +1,1007 @@
+// Code generated by "msgpackgen -type=Person,Address,Point". DO NOT EDIT.
+
+package generated
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+)
+
+// DecodeMsgpack decodes the next value from d into x. It implements the interface used by fastmsgpack.Unmarshal.
+func (x *Person) DecodeMsgpack(d *fastmsgpack.Decoder) error {
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; elements > i; i++ {
+		k, err := d.DecodeString()
+		if err != nil {
+			if err == fastmsgpack.ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		switch k {
+		case "id":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Base.ID = 0
+				err = d.Skip()
+			} else {
+				var v uint32
+				var n uint64
+				if n, err = d.DecodeUint(); err == nil {
+					if n > 1<<32-1 {
+						err = fmt.Errorf("fastmsgpack: %d overflows uint32", n)
+					}
+					v = uint32(n)
+				}
+				if err == nil {
+					x.Base.ID = v
+				}
+			}
+		case "name":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Name = ""
+				err = d.Skip()
+			} else {
+				var v string
+				v, err = d.DecodeString()
+				if err == nil {
+					x.Name = v
+				}
+			}
+		case "nick":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Nick = ""
+				err = d.Skip()
+			} else {
+				var v string
+				v, err = d.DecodeString()
+				if err == nil {
+					x.Nick = v
+				}
+			}
+		case "age":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Age = nil
+				err = d.Skip()
+			} else {
+				var v *int
+				v, err = _Person_decodePointer0(d)
+				if err == nil {
+					x.Age = v
+				}
+			}
+		case "status":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Status = 0
+				err = d.Skip()
+			} else {
+				var v Status
+				var n int
+				if n, err = d.DecodeIntChecked(); err == nil {
+					if n < -1<<7 || n > 1<<7-1 {
+						err = fmt.Errorf("fastmsgpack: %d overflows Status", n)
+					}
+					v = Status(n)
+				} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+					u, _ := d.DecodeUint()
+					err = fmt.Errorf("fastmsgpack: %d overflows Status", u)
+				}
+				if err == nil {
+					x.Status = v
+				}
+			}
+		case "score":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Score = 0
+				err = d.Skip()
+			} else {
+				var v float64
+				v, err = d.DecodeFloat64()
+				if err == nil {
+					x.Score = v
+				}
+			}
+		case "active":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Active = false
+				err = d.Skip()
+			} else {
+				var v bool
+				v, err = d.DecodeBool()
+				if err == nil {
+					x.Active = v
+				}
+			}
+		case "tags":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Tags = nil
+				err = d.Skip()
+			} else {
+				var v []string
+				v, err = _Person_decodeSlice1(d)
+				if err == nil {
+					x.Tags = v
+				}
+			}
+		case "addresses":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Addresses = nil
+				err = d.Skip()
+			} else {
+				var v []Address
+				v, err = _Person_decodeSlice2(d)
+				if err == nil {
+					x.Addresses = v
+				}
+			}
+		case "home":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Home = nil
+				err = d.Skip()
+			} else {
+				var v *Address
+				v, err = _Person_decodePointer3(d)
+				if err == nil {
+					x.Home = v
+				}
+			}
+		case "extra":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Extra = nil
+				err = d.Skip()
+			} else {
+				var v map[string]any
+				v, err = _Person_decodeMap4(d)
+				if err == nil {
+					x.Extra = v
+				}
+			}
+		case "lookup":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Lookup = nil
+				err = d.Skip()
+			} else {
+				var v map[string][]int
+				v, err = _Person_decodeMap5(d)
+				if err == nil {
+					x.Lookup = v
+				}
+			}
+		case "born":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Born = time.Time{}
+				err = d.Skip()
+			} else {
+				var v time.Time
+				v, err = d.DecodeTime()
+				if err == nil {
+					x.Born = v
+				}
+			}
+		case "avatar":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Avatar = nil
+				err = d.Skip()
+			} else {
+				var v []byte
+				v, err = d.DecodeBytes()
+				if err == nil {
+					x.Avatar = v
+				}
+			}
+		case "Untagged":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Untagged = false
+				err = d.Skip()
+			} else {
+				var v bool
+				v, err = d.DecodeBool()
+				if err == nil {
+					x.Untagged = v
+				}
+			}
+		case "pair":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Pair = [2]int{}
+				err = d.Skip()
+			} else {
+				var v [2]int
+				err = d.Unmarshal(&v)
+				if err == nil {
+					x.Pair = v
+				}
+			}
+		case "location":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Location = Point{}
+				err = d.Skip()
+			} else {
+				var v Point
+				err = v.DecodeMsgpack(d)
+				if err == nil {
+					x.Location = v
+				}
+			}
+		case "visited":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Visited = Date{}
+				err = d.Skip()
+			} else {
+				var v Date
+				err = d.Unmarshal(&v)
+				if err == nil {
+					x.Visited = v
+				}
+			}
+		default:
+			err = d.Skip()
+		}
+		if err == fastmsgpack.ErrVoid {
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMsgpack appends the msgpack representation of x to dst with the default EncodeOptions.
+func (x Person) AppendMsgpack(dst []byte) ([]byte, error) {
+	return x.AppendMsgpackWithOptions(dst, fastmsgpack.EncodeOptions{})
+}
+
+// AppendMsgpackWithOptions appends the msgpack representation of x to dst. It implements the interface used by fastmsgpack.Encode.
+func (x Person) AppendMsgpackWithOptions(dst []byte, o fastmsgpack.EncodeOptions) ([]byte, error) {
+	var err error
+	n := 15
+	if x.Nick != "" {
+		n++
+	}
+	if x.Home != nil {
+		n++
+	}
+	if !x.Visited.IsZero() {
+		n++
+	}
+	dst, err = o.EncodeMapLen(dst, n)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "id")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeUint(dst, uint(x.Base.ID))
+	dst, err = o.EncodeString(dst, "name")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, x.Name)
+	if err != nil {
+		return nil, err
+	}
+	if x.Nick != "" {
+		dst, err = o.EncodeString(dst, "nick")
+		if err != nil {
+			return nil, err
+		}
+		dst, err = o.EncodeString(dst, x.Nick)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dst, err = o.EncodeString(dst, "age")
+	if err != nil {
+		return nil, err
+	}
+	if x.Age == nil {
+		dst = o.EncodeNil(dst)
+	} else {
+		dst = o.EncodeInt(dst, *x.Age)
+	}
+	dst, err = o.EncodeString(dst, "status")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeInt(dst, int(x.Status))
+	dst, err = o.EncodeString(dst, "score")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeFloat64(dst, x.Score)
+	dst, err = o.EncodeString(dst, "active")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeBool(dst, x.Active)
+	dst, err = o.EncodeString(dst, "tags")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = _Person_appendSlice7(o, dst, x.Tags)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "addresses")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = _Person_appendSlice8(o, dst, x.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	if x.Home != nil {
+		dst, err = o.EncodeString(dst, "home")
+		if err != nil {
+			return nil, err
+		}
+		dst, err = (*x.Home).AppendMsgpackWithOptions(dst, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dst, err = o.EncodeString(dst, "extra")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = _Person_appendMap9(o, dst, x.Extra)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "lookup")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = _Person_appendMap10(o, dst, x.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "born")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeTime(dst, x.Born)
+	dst, err = o.EncodeString(dst, "avatar")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeBytes(dst, x.Avatar)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "Untagged")
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeBool(dst, x.Untagged)
+	dst, err = o.EncodeString(dst, "pair")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.Encode(dst, x.Pair)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "location")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = x.Location.AppendMsgpackWithOptions(dst, o)
+	if err != nil {
+		return nil, err
+	}
+	if !x.Visited.IsZero() {
+		dst, err = o.EncodeString(dst, "visited")
+		if err != nil {
+			return nil, err
+		}
+		dst, err = o.Encode(dst, x.Visited)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// DecodeMsgpack decodes the next value from d into x. It implements the interface used by fastmsgpack.Unmarshal.
+func (x *Address) DecodeMsgpack(d *fastmsgpack.Decoder) error {
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; elements > i; i++ {
+		k, err := d.DecodeString()
+		if err != nil {
+			if err == fastmsgpack.ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		switch k {
+		case "street":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Street = ""
+				err = d.Skip()
+			} else {
+				var v string
+				v, err = d.DecodeString()
+				if err == nil {
+					x.Street = v
+				}
+			}
+		case "number":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Number = 0
+				err = d.Skip()
+			} else {
+				var v int
+				var n int
+				if n, err = d.DecodeIntChecked(); err == nil {
+					v = n
+				} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+					u, _ := d.DecodeUint()
+					err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+				}
+				if err == nil {
+					x.Number = v
+				}
+			}
+		default:
+			err = d.Skip()
+		}
+		if err == fastmsgpack.ErrVoid {
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMsgpack appends the msgpack representation of x to dst with the default EncodeOptions.
+func (x Address) AppendMsgpack(dst []byte) ([]byte, error) {
+	return x.AppendMsgpackWithOptions(dst, fastmsgpack.EncodeOptions{})
+}
+
+// AppendMsgpackWithOptions appends the msgpack representation of x to dst. It implements the interface used by fastmsgpack.Encode.
+func (x Address) AppendMsgpackWithOptions(dst []byte, o fastmsgpack.EncodeOptions) ([]byte, error) {
+	var err error
+	n := 1
+	if x.Number != 0 {
+		n++
+	}
+	dst, err = o.EncodeMapLen(dst, n)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, "street")
+	if err != nil {
+		return nil, err
+	}
+	dst, err = o.EncodeString(dst, x.Street)
+	if err != nil {
+		return nil, err
+	}
+	if x.Number != 0 {
+		dst, err = o.EncodeString(dst, "number")
+		if err != nil {
+			return nil, err
+		}
+		dst = o.EncodeInt(dst, x.Number)
+	}
+	return dst, nil
+}
+
+// DecodeMsgpack decodes the next value from d into x. It implements the interface used by fastmsgpack.Unmarshal.
+func (x *Point) DecodeMsgpack(d *fastmsgpack.Decoder) error {
+	if d.PeekType() == fastmsgpack.TypeArray {
+		elements, err := d.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		for i := 0; elements > i; i++ {
+			switch i {
+			case 0:
+				if d.PeekType() == fastmsgpack.TypeNil {
+					x.X = 0
+					err = d.Skip()
+				} else {
+					var v int
+					var n int
+					if n, err = d.DecodeIntChecked(); err == nil {
+						v = n
+					} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+						u, _ := d.DecodeUint()
+						err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+					}
+					if err == nil {
+						x.X = v
+					}
+				}
+			case 1:
+				if d.PeekType() == fastmsgpack.TypeNil {
+					x.Y = 0
+					err = d.Skip()
+				} else {
+					var v int
+					var n int
+					if n, err = d.DecodeIntChecked(); err == nil {
+						v = n
+					} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+						u, _ := d.DecodeUint()
+						err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+					}
+					if err == nil {
+						x.Y = v
+					}
+				}
+			case 2:
+				if d.PeekType() == fastmsgpack.TypeNil {
+					x.Label = ""
+					err = d.Skip()
+				} else {
+					var v string
+					v, err = d.DecodeString()
+					if err == nil {
+						x.Label = v
+					}
+				}
+			default:
+				err = d.Skip()
+			}
+			if err == fastmsgpack.ErrVoid {
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; elements > i; i++ {
+		k, err := d.DecodeString()
+		if err != nil {
+			if err == fastmsgpack.ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		switch k {
+		case "X":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.X = 0
+				err = d.Skip()
+			} else {
+				var v int
+				var n int
+				if n, err = d.DecodeIntChecked(); err == nil {
+					v = n
+				} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+					u, _ := d.DecodeUint()
+					err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+				}
+				if err == nil {
+					x.X = v
+				}
+			}
+		case "Y":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Y = 0
+				err = d.Skip()
+			} else {
+				var v int
+				var n int
+				if n, err = d.DecodeIntChecked(); err == nil {
+					v = n
+				} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+					u, _ := d.DecodeUint()
+					err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+				}
+				if err == nil {
+					x.Y = v
+				}
+			}
+		case "Label":
+			if d.PeekType() == fastmsgpack.TypeNil {
+				x.Label = ""
+				err = d.Skip()
+			} else {
+				var v string
+				v, err = d.DecodeString()
+				if err == nil {
+					x.Label = v
+				}
+			}
+		default:
+			err = d.Skip()
+		}
+		if err == fastmsgpack.ErrVoid {
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMsgpack appends the msgpack representation of x to dst with the default EncodeOptions.
+func (x Point) AppendMsgpack(dst []byte) ([]byte, error) {
+	return x.AppendMsgpackWithOptions(dst, fastmsgpack.EncodeOptions{})
+}
+
+// AppendMsgpackWithOptions appends the msgpack representation of x to dst. It implements the interface used by fastmsgpack.Encode.
+func (x Point) AppendMsgpackWithOptions(dst []byte, o fastmsgpack.EncodeOptions) ([]byte, error) {
+	var err error
+	dst, err = o.EncodeArrayLen(dst, 3)
+	if err != nil {
+		return nil, err
+	}
+	dst = o.EncodeInt(dst, x.X)
+	dst = o.EncodeInt(dst, x.Y)
+	dst, err = o.EncodeString(dst, x.Label)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+func _Person_decodePointer0(d *fastmsgpack.Decoder) (*int, error) {
+	var err error
+	var e int
+	if d.PeekType() == fastmsgpack.TypeNil {
+		e = 0
+		err = d.Skip()
+	} else {
+		var v int
+		var n int
+		if n, err = d.DecodeIntChecked(); err == nil {
+			v = n
+		} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+			u, _ := d.DecodeUint()
+			err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+		}
+		if err == nil {
+			e = v
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func _Person_decodeSlice1(d *fastmsgpack.Decoder) ([]string, error) {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, elements)
+	for i := 0; elements > i; i++ {
+		var e string
+		if d.PeekType() == fastmsgpack.TypeNil {
+			e = ""
+			err = d.Skip()
+		} else {
+			var v string
+			v, err = d.DecodeString()
+			if err == nil {
+				e = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+func _Person_decodeSlice2(d *fastmsgpack.Decoder) ([]Address, error) {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Address, 0, elements)
+	for i := 0; elements > i; i++ {
+		var e Address
+		if d.PeekType() == fastmsgpack.TypeNil {
+			e = Address{}
+			err = d.Skip()
+		} else {
+			var v Address
+			err = v.DecodeMsgpack(d)
+			if err == nil {
+				e = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+func _Person_decodePointer3(d *fastmsgpack.Decoder) (*Address, error) {
+	var err error
+	var e Address
+	if d.PeekType() == fastmsgpack.TypeNil {
+		e = Address{}
+		err = d.Skip()
+	} else {
+		var v Address
+		err = v.DecodeMsgpack(d)
+		if err == nil {
+			e = v
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func _Person_decodeMap4(d *fastmsgpack.Decoder) (map[string]any, error) {
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]any, elements)
+	for i := 0; elements > i; i++ {
+		var k string
+		if d.PeekType() == fastmsgpack.TypeNil {
+			k = ""
+			err = d.Skip()
+		} else {
+			var v string
+			v, err = d.DecodeString()
+			if err == nil {
+				k = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e any
+		if d.PeekType() == fastmsgpack.TypeNil {
+			e = nil
+			err = d.Skip()
+		} else {
+			var v any
+			v, err = d.DecodeValue()
+			if err == nil {
+				e = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret[k] = e
+	}
+	return ret, nil
+}
+
+func _Person_decodeSlice6(d *fastmsgpack.Decoder) ([]int, error) {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]int, 0, elements)
+	for i := 0; elements > i; i++ {
+		var e int
+		if d.PeekType() == fastmsgpack.TypeNil {
+			e = 0
+			err = d.Skip()
+		} else {
+			var v int
+			var n int
+			if n, err = d.DecodeIntChecked(); err == nil {
+				v = n
+			} else if errors.Is(err, fastmsgpack.ErrIntOverflow) {
+				u, _ := d.DecodeUint()
+				err = fmt.Errorf("fastmsgpack: %d overflows int", u)
+			}
+			if err == nil {
+				e = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+func _Person_decodeMap5(d *fastmsgpack.Decoder) (map[string][]int, error) {
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]int, elements)
+	for i := 0; elements > i; i++ {
+		var k string
+		if d.PeekType() == fastmsgpack.TypeNil {
+			k = ""
+			err = d.Skip()
+		} else {
+			var v string
+			v, err = d.DecodeString()
+			if err == nil {
+				k = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e []int
+		if d.PeekType() == fastmsgpack.TypeNil {
+			e = nil
+			err = d.Skip()
+		} else {
+			var v []int
+			v, err = _Person_decodeSlice6(d)
+			if err == nil {
+				e = v
+			}
+		}
+		if err == fastmsgpack.ErrVoid {
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret[k] = e
+	}
+	return ret, nil
+}
+
+func _Person_appendSlice7(o fastmsgpack.EncodeOptions, dst []byte, s []string) ([]byte, error) {
+	dst, err := o.EncodeArrayLen(dst, len(s))
+	if err != nil {
+		return nil, err
+	}
+	for i := range s {
+		dst, err = o.EncodeString(dst, s[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func _Person_appendSlice8(o fastmsgpack.EncodeOptions, dst []byte, s []Address) ([]byte, error) {
+	dst, err := o.EncodeArrayLen(dst, len(s))
+	if err != nil {
+		return nil, err
+	}
+	for i := range s {
+		dst, err = s[i].AppendMsgpackWithOptions(dst, o)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func _Person_appendMap9(o fastmsgpack.EncodeOptions, dst []byte, m map[string]any) ([]byte, error) {
+	dst, err := o.EncodeMapLen(dst, len(m))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		dst, err = o.EncodeString(dst, k)
+		if err != nil {
+			return nil, err
+		}
+		dst, err = o.Encode(dst, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func _Person_appendSlice11(o fastmsgpack.EncodeOptions, dst []byte, s []int) ([]byte, error) {
+	dst, err := o.EncodeArrayLen(dst, len(s))
+	if err != nil {
+		return nil, err
+	}
+	for i := range s {
+		dst = o.EncodeInt(dst, s[i])
+	}
+	return dst, nil
+}
+
+func _Person_appendMap10(o fastmsgpack.EncodeOptions, dst []byte, m map[string][]int) ([]byte, error) {
+	dst, err := o.EncodeMapLen(dst, len(m))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		dst, err = o.EncodeString(dst, k)
+		if err != nil {
+			return nil, err
+		}
+		dst, err = _Person_appendSlice11(o, dst, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
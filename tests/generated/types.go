@@ -0,0 +1,53 @@
+// Package generated contains types with msgpackgen generated methods for the tests.
+package generated
+
+import "time"
+
+//go:generate go run github.com/hexon/fastmsgpack/msgpackgen -type=Person,Address,Point
+
+type Status int8
+
+type Base struct {
+	ID uint32 `msgpack:"id"`
+}
+
+type Person struct {
+	Base
+	Name      string           `msgpack:"name"`
+	Nick      string           `msgpack:"nick,omitempty"`
+	Age       *int             `msgpack:"age"`
+	Status    Status           `msgpack:"status"`
+	Score     float64          `msgpack:"score"`
+	Active    bool             `msgpack:"active"`
+	Tags      []string         `msgpack:"tags"`
+	Addresses []Address        `msgpack:"addresses"`
+	Home      *Address         `msgpack:"home,omitempty"`
+	Extra     map[string]any   `msgpack:"extra"`
+	Lookup    map[string][]int `msgpack:"lookup"`
+	Born      time.Time        `msgpack:"born"`
+	Avatar    []byte           `msgpack:"avatar"`
+	Ignored   string           `msgpack:"-"`
+	Untagged  bool
+	Pair      [2]int `msgpack:"pair"`
+	Location  Point  `msgpack:"location"`
+	Visited   Date   `msgpack:"visited,omitempty"`
+}
+
+type Address struct {
+	Street string `msgpack:"street"`
+	Number int    `msgpack:"number,omitempty"`
+}
+
+type Point struct {
+	_msgpack struct{} `msgpack:",as_array"`
+	X, Y     int
+	Label    string
+}
+
+type Date struct {
+	Year, Month, Day int
+}
+
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
@@ -0,0 +1,199 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/hexon/fastmsgpack/debug"
+	"github.com/hexon/fastmsgpack/msgpackconverter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsHugeHeader(t *testing.T) {
+	// An array32 and map32 header claiming 4 billion elements, without the data to back it up.
+	for _, data := range [][]byte{{0xdd, 0xff, 0xff, 0xff, 0xff, 0xc0}, {0xdf, 0xff, 0xff, 0xff, 0xff, 0xc0, 0xc0}} {
+		_, err := fastmsgpack.Decode(data)
+		require.Error(t, err)
+
+		var v any
+		require.Error(t, fastmsgpack.Unmarshal(data, &v))
+
+		_, err = fastmsgpack.Canonical(nil, data, fastmsgpack.EncodeOptions{})
+		require.Error(t, err)
+
+		require.Error(t, msgpackconverter.NewJSONConverter().Convert(io.Discard, data))
+		require.Error(t, debug.Fdump(io.Discard, data))
+
+		d := fastmsgpack.NewDecoder(data)
+		if data[0] == 0xdd {
+			_, err = d.DecodeArrayLen()
+		} else {
+			_, err = d.DecodeMapLen()
+		}
+		require.Error(t, err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	nested, err := fastmsgpack.Encode(nil, map[string]any{"a": []any{[]any{[]any{1}}}})
+	require.NoError(t, err)
+	wide, err := fastmsgpack.Encode(nil, []any{1, 2, 3, 4, 5})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		limits fastmsgpack.Limits
+		ok     bool
+	}{
+		{"depth ok", nested, fastmsgpack.Limits{MaxDepth: 4}, true},
+		{"depth exceeded", nested, fastmsgpack.Limits{MaxDepth: 3}, false},
+		{"elements ok", wide, fastmsgpack.Limits{MaxElements: 5}, true},
+		{"elements exceeded", wide, fastmsgpack.Limits{MaxElements: 4}, false},
+		{"bytes ok", nested, fastmsgpack.Limits{MaxBytes: len(nested)}, true},
+		{"bytes exceeded", nested, fastmsgpack.Limits{MaxBytes: len(nested) - 1}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opt := fastmsgpack.WithLimits(tc.limits)
+			check := func(err error) {
+				t.Helper()
+				if tc.ok {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+				}
+			}
+
+			_, err := fastmsgpack.Decode(tc.data, opt)
+			check(err)
+
+			_, err = fastmsgpack.NewDecoder(tc.data, opt).DecodeValue()
+			if tc.limits.MaxBytes == 0 {
+				check(err)
+			}
+
+			var v any
+			check(fastmsgpack.Unmarshal(tc.data, &v, opt))
+
+			_, err = fastmsgpack.Canonical(nil, tc.data, fastmsgpack.EncodeOptions{}, opt)
+			check(err)
+
+			check(msgpackconverter.NewJSONConverter(opt).Convert(io.Discard, tc.data))
+			check(debug.Fdump(io.Discard, tc.data, opt))
+
+			if tc.limits.MaxElements == 0 {
+				// The Resolver only accepts maps.
+				r, err := fastmsgpack.NewResolver([]string{"a"}, opt)
+				require.NoError(t, err)
+				_, err = r.Resolve(tc.data)
+				check(err)
+			}
+		})
+	}
+}
+
+func TestLimitsDecoderMethods(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, []any{[]any{1, 2, 3}})
+	require.NoError(t, err)
+
+	d := fastmsgpack.NewDecoder(data, fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 1}))
+	_, err = d.DecodeArrayLen()
+	require.NoError(t, err)
+	_, err = d.DecodeArrayLen()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+
+	d = fastmsgpack.NewDecoder(data, fastmsgpack.WithLimits(fastmsgpack.Limits{MaxElements: 2}))
+	_, err = d.DecodeArrayLen()
+	require.NoError(t, err)
+	_, err = d.DecodeArrayLen()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+}
+
+func TestLimitsDepthSingleElementChain(t *testing.T) {
+	// Containers with a single element are the last child of their parent, which must still count as a level.
+	data := []byte{0x91, 0x91, 0x91, 0x91, 0x01}
+	limit := fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 2})
+
+	d := fastmsgpack.NewDecoder(data, limit)
+	for i := 0; 2 > i; i++ {
+		_, err := d.DecodeArrayLen()
+		require.NoError(t, err)
+	}
+	_, err := d.DecodeArrayLen()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+
+	var typed [][][][]int
+	require.ErrorIs(t, fastmsgpack.Unmarshal(data, &typed, limit), fastmsgpack.ErrLimitExceeded)
+	require.NoError(t, fastmsgpack.Unmarshal(data, &typed, fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 4})))
+	require.Equal(t, [][][][]int{{{{1}}}}, typed)
+
+	type node struct {
+		Child *node `msgpack:"child"`
+	}
+	nested, err := fastmsgpack.Encode(nil, map[string]any{"child": map[string]any{"child": map[string]any{"child": nil}}})
+	require.NoError(t, err)
+	var n node
+	require.ErrorIs(t, fastmsgpack.Unmarshal(nested, &n, limit), fastmsgpack.ErrLimitExceeded)
+	require.NoError(t, fastmsgpack.Unmarshal(nested, &n, fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 3})))
+
+	// The length-prefixed entry around a map counts as a level too.
+	wrapped, err := fastmsgpack.LengthEncode(nil, []byte{0x81, 0xa1, 'a', 0x91, 0x01})
+	require.NoError(t, err)
+	d = fastmsgpack.NewDecoder(wrapped, limit)
+	_, err = d.DecodeMapLen()
+	require.NoError(t, err)
+	_, err = d.DecodeString()
+	require.NoError(t, err)
+	_, err = d.DecodeArrayLen()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+
+	r, err := fastmsgpack.NewResolver([]string{"a[0][0][0]"}, limit)
+	require.NoError(t, err)
+	_, err = r.Resolve(append([]byte{0x81, 0xa1, 'a'}, data...))
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+}
+
+func TestLimitsSkippedDeepNesting(t *testing.T) {
+	// {"x": [[[...nil...]]], "y": 1} with 20 million nested arrays under x, which is never decoded but only skipped.
+	const depth = 20_000_000
+	data := []byte{0x82, 0xa1, 'x'}
+	data = append(data, bytes.Repeat([]byte{0x91}, depth)...)
+	data = append(data, 0xc0, 0xa1, 'y', 0x01)
+
+	r, err := fastmsgpack.NewResolver([]string{"y"}, fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 5}))
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{1}, found)
+
+	d := fastmsgpack.NewDecoder(data[3:])
+	require.NoError(t, d.Skip())
+	raw, err := d.PeekRaw()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xa1, 'y'}, raw)
+}
+
+func TestLimitsStreamDecoder(t *testing.T) {
+	small, err := fastmsgpack.Encode(nil, "small")
+	require.NoError(t, err)
+	big, err := fastmsgpack.Encode(nil, string(bytes.Repeat([]byte{'x'}, 10000)))
+	require.NoError(t, err)
+
+	sd := fastmsgpack.NewStreamDecoder(bytes.NewReader(append(small, big...)), fastmsgpack.WithLimits(fastmsgpack.Limits{MaxBytes: 100}))
+	b, err := sd.NextRaw()
+	require.NoError(t, err)
+	require.Equal(t, small, b)
+	_, err = sd.NextRaw()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+
+	// A value that's only one byte too large fits in the buffer completely.
+	justTooBig, err := fastmsgpack.Encode(nil, string(bytes.Repeat([]byte{'x'}, 99)))
+	require.NoError(t, err)
+	require.Len(t, justTooBig, 101)
+	sd = fastmsgpack.NewStreamDecoder(bytes.NewReader(justTooBig), fastmsgpack.WithLimits(fastmsgpack.Limits{MaxBytes: 100}))
+	_, err = sd.NextRaw()
+	require.ErrorIs(t, err, fastmsgpack.ErrLimitExceeded)
+}
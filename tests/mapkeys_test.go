@@ -0,0 +1,108 @@
+package msgpack_test
+
+import (
+	"testing"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeIntKeyedMap(t *testing.T) []byte {
+	t.Helper()
+	// Construct it by hand to control the key order, so the string key comes first.
+	dst, err := fastmsgpack.EncodeOptions{}.EncodeMapLen(nil, 4)
+	require.NoError(t, err)
+	for _, kv := range [][2]any{
+		{"name", "sparse"},
+		{3, "three"},
+		{-1, map[string]any{"deep": true}},
+		{[]byte("bin"), fastmsgpack.Extension{Type: 19}},
+	} {
+		dst, err = fastmsgpack.Encode(dst, kv[0])
+		require.NoError(t, err)
+		dst, err = fastmsgpack.Encode(dst, kv[1])
+		require.NoError(t, err)
+	}
+	return dst
+}
+
+func TestNonStringKeys(t *testing.T) {
+	data := encodeIntKeyedMap(t)
+
+	_, err := fastmsgpack.Decode(data)
+	require.Error(t, err)
+
+	v, err := fastmsgpack.Decode(data, fastmsgpack.WithNonStringKeys())
+	require.NoError(t, err)
+	require.Equal(t, map[any]any{"name": "sparse", 3: "three", -1: map[string]any{"deep": true}}, v)
+
+	v, err = fastmsgpack.Decode([]byte{0x81, 0xa1, 'a', 0x01}, fastmsgpack.WithNonStringKeys())
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 1}, v)
+
+	_, err = fastmsgpack.Decode([]byte{0x81, 0x90, 0x01}, fastmsgpack.WithNonStringKeys())
+	require.Error(t, err)
+}
+
+func TestNonStringKeysDecoder(t *testing.T) {
+	data := encodeIntKeyedMap(t)
+
+	d := fastmsgpack.NewDecoder(data)
+	n, err := d.DecodeMapLen()
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	var keys []any
+	for i := 0; n > i; i++ {
+		k, err := d.DecodeMapKey()
+		require.NoError(t, err)
+		keys = append(keys, k)
+		require.NoError(t, d.Skip())
+	}
+	require.Equal(t, []any{"name", 3, -1, "bin"}, keys)
+
+	rawKeys, values, err := fastmsgpack.SplitMapRaw(data)
+	require.NoError(t, err)
+	require.Len(t, values, 4)
+	require.Equal(t, []byte{0x03}, rawKeys[1])
+	require.Equal(t, []byte{0xff}, rawKeys[2])
+}
+
+func TestNonStringKeysResolver(t *testing.T) {
+	data := append([]byte{0x81, 0xa6, 's', 'p', 'a', 'r', 's', 'e'}, encodeIntKeyedMap(t)...)
+
+	r, err := fastmsgpack.NewResolver([]string{"sparse.3", "sparse.-1.deep", "sparse.name", "sparse.4"}, fastmsgpack.WithNonStringKeys())
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{"three", true, "sparse", nil}, found)
+
+	// Without WithNonStringKeys integer keys aren't matched, so existing Resolvers behave as before.
+	r, err = fastmsgpack.NewResolver([]string{"sparse.3"})
+	require.NoError(t, err)
+	_, err = r.Resolve(data)
+	require.Error(t, err)
+}
+
+func TestNonStringKeysUnmatchable(t *testing.T) {
+	// {true: 1, 1.5: 2, nil: 3, bin("x"): 4, "a": 5}
+	data := []byte{0x85, 0xc3, 0x01, 0xca, 0x3f, 0xc0, 0x00, 0x00, 0x02, 0xc0, 0x03, 0xc4, 0x01, 'x', 0x04, 0xa1, 'a', 0x05}
+
+	r, err := fastmsgpack.NewResolver([]string{"a"}, fastmsgpack.WithNonStringKeys())
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{5}, found)
+
+	selected, err := r.Select(nil, data)
+	require.NoError(t, err)
+	v, err := fastmsgpack.Decode(selected)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 5}, v)
+
+	excepted, err := r.SelectExcept(nil, data)
+	require.NoError(t, err)
+	rawKeys, values, err := fastmsgpack.SplitMapRaw(excepted)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{0xc3}, {0xca, 0x3f, 0xc0, 0x00, 0x00}, {0xc0}, {0xc4, 0x01, 'x'}}, rawKeys)
+	require.Equal(t, [][]byte{{0x01}, {0x02}, {0x03}, {0x04}}, values)
+}
@@ -0,0 +1,112 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/hexon/fastmsgpack/tests/generated"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	age := 42
+	in := generated.Person{
+		Base:      generated.Base{ID: 17},
+		Name:      "Jan",
+		Age:       &age,
+		Status:    -3,
+		Score:     7.5,
+		Active:    true,
+		Tags:      []string{"a", "b"},
+		Addresses: []generated.Address{{Street: "Main Street", Number: 1}, {Street: "Second Street"}},
+		Home:      &generated.Address{Street: "Home Street"},
+		Extra:     map[string]any{"x": 1},
+		Lookup:    map[string][]int{"k": {1, 2}},
+		Born:      time.Unix(1700000000, 0),
+		Avatar:    []byte{1, 2, 3},
+		Ignored:   "not encoded",
+		Untagged:  true,
+		Pair:      [2]int{3, 4},
+		Location:  generated.Point{X: 1, Y: 2, Label: "here"},
+	}
+	data, err := fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+
+	var out generated.Person
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out))
+	in.Ignored = ""
+	require.Equal(t, in, out)
+
+	decoded, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	m := decoded.(map[string]any)
+	require.NotContains(t, m, "nick")
+	require.NotContains(t, m, "Ignored")
+	require.Equal(t, 17, m["id"])
+	require.Equal(t, map[string]any{"street": "Home Street"}, m["home"])
+	require.Equal(t, []any{1, 2, "here"}, m["location"])
+	require.NotContains(t, m, "visited")
+
+	in.Visited = generated.Date{Year: 2024, Month: 2, Day: 29}
+	data, err = fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+	out = generated.Person{}
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out))
+	require.Equal(t, in, out)
+}
+
+func TestGeneratedEncodeOptions(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"street", "Main Street"})
+	eo := fastmsgpack.EncodeOptions{Dict: map[string]int{"street": 0, "Main Street": 1}}
+	in := generated.Person{Name: "Jan", Home: &generated.Address{Street: "Main Street"}}
+
+	plain, err := fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+	data, err := eo.Encode(nil, in)
+	require.NoError(t, err)
+	require.Less(t, len(data), len(plain))
+	direct, err := in.AppendMsgpackWithOptions(nil, eo)
+	require.NoError(t, err)
+	require.Equal(t, data, direct)
+
+	var out generated.Person
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out, fastmsgpack.WithDict(dict)))
+	require.Equal(t, "Jan", out.Name)
+	require.Equal(t, in.Home, out.Home)
+}
+
+func TestGeneratedExtensions(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"name":   fastmsgpack.Extension{Type: 20, Data: []byte{1}},
+		"status": fastmsgpack.Extension{Type: 19},
+		"tags":   []any{"a", fastmsgpack.Extension{Type: 19}, "b"},
+		"age":    nil,
+	})
+	require.NoError(t, err)
+	data, err = fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+
+	age := 5
+	out := generated.Person{Status: 1, Age: &age}
+	d := fastmsgpack.NewDecoder(data, fastmsgpack.WithInjection(1, []byte{0xa3, 'J', 'a', 'n'}))
+	require.NoError(t, out.DecodeMsgpack(d))
+	require.Equal(t, generated.Person{Name: "Jan", Status: 1, Tags: []string{"a", "b"}}, out)
+}
+
+func TestGeneratedUintRange(t *testing.T) {
+	for _, id := range []any{-1, uint64(1) << 33} {
+		data, err := fastmsgpack.Encode(nil, map[string]any{"id": id})
+		require.NoError(t, err)
+		var out generated.Person
+		require.Error(t, fastmsgpack.Unmarshal(data, &out))
+	}
+
+	// Signed fields must reject a uint 64 above math.MaxInt64 rather than wrap around.
+	for _, field := range []string{"age", "status"} {
+		data, err := fastmsgpack.Encode(nil, map[string]any{field: uint64(1) << 63})
+		require.NoError(t, err)
+		var out generated.Person
+		require.ErrorContains(t, fastmsgpack.Unmarshal(data, &out), "9223372036854775808 overflows")
+	}
+}
@@ -0,0 +1,910 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePerson(t *testing.T) []byte {
+	t.Helper()
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name": "Jan",
+			"addresses": []any{
+				map[string]any{"street": "Main", "number": 1},
+				map[string]any{"street": "Side", "number": 2},
+				map[string]any{"street": "Back", "number": 3},
+			},
+		},
+		"items": []any{
+			map[string]any{"id": 10, "tags": []any{"a", "b"}},
+			map[string]any{"id": 11, "tags": []any{"c"}},
+		},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestResolverArrayIndexes(t *testing.T) {
+	data := encodePerson(t)
+	lengthEncoded, err := fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+
+	fields := []string{"person.addresses[0].street", "person.addresses[-1].number", "items[*].id", "items[*].tags[0]", "items[5]", "person.addresses[-1].street", "person.addresses[-3].number"}
+	r, err := fastmsgpack.NewResolver(fields)
+	require.NoError(t, err)
+	wanted := []any{"Main", 3, []any{10, 11}, []any{"a", "c"}, nil, "Back", 1}
+
+	for _, d := range [][]byte{data, lengthEncoded} {
+		found, err := r.Resolve(d)
+		require.NoError(t, err)
+		require.Equal(t, wanted, found)
+
+		selected, err := r.Select(nil, d)
+		require.NoError(t, err)
+		found, err = r.Resolve(selected)
+		require.NoError(t, err)
+		require.Equal(t, wanted, found)
+	}
+
+	described, _ := r.Describe()
+	require.Equal(t, fields, described)
+}
+
+func TestResolverArrayIndexesNested(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"matrix": []any{[]any{1, 2}, []any{3, 4}, fastmsgpack.Extension{Type: 19}, []any{5}},
+	})
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"matrix[*][0]", "matrix[1][1]", "matrix[-1]"})
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{1, 3, 5}, 4, []any{5}}, found)
+}
+
+func TestResolverArrayIndexErrors(t *testing.T) {
+	for _, f := range []string{"a[", "a[x]", "a[1", "a[1]x"} {
+		_, err := fastmsgpack.NewResolver([]string{f})
+		require.Error(t, err, f)
+	}
+	_, err := fastmsgpack.NewResolver([]string{"a[0]", "a.b"})
+	require.Error(t, err)
+	_, err = fastmsgpack.NewResolver([]string{"a[0].b", "a[0].b"})
+	require.Error(t, err)
+	_, err = fastmsgpack.NewResolver([]string{"a[0]", "a[*]", "a[-1]"})
+	require.NoError(t, err)
+}
+
+func TestResolverVoid(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"a":     fastmsgpack.Extension{Type: 19},
+		"b":     map[string]any{"c": fastmsgpack.Extension{Type: 19}, "d": 1},
+		"e":     2,
+		"items": []any{fastmsgpack.Extension{Type: 19}, map[string]any{"id": 1}},
+	})
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"a.x", "b.c.x", "b.d", "e", "items[*].id", "items[0].id"})
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{nil, nil, 1, 2, []any{1}, nil}, found)
+}
+
+func TestResolverEscaping(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"domains": map[string]any{
+			"example.com": map[string]any{"owner": "Jan"},
+			"a[0]":        1,
+			`back\slash`:  2,
+		},
+	})
+	require.NoError(t, err)
+
+	fields := []string{`domains.example\.com.owner`, `domains.a\[0]`, `domains.back\\slash`}
+	r, err := fastmsgpack.NewResolver(fields)
+	require.NoError(t, err)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{"Jan", 1, 2}, found)
+	described, _ := r.Describe()
+	require.Equal(t, fields, described)
+
+	r, err = fastmsgpack.NewResolverFromPaths([][]string{{"domains", "example.com", "owner"}, {"domains", "a[0]"}, {"domains", `back\slash`}})
+	require.NoError(t, err)
+	found, err = r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{"Jan", 1, 2}, found)
+	described, _ = r.Describe()
+	require.Equal(t, fields, described)
+
+	_, err = fastmsgpack.NewResolver([]string{`domains\`})
+	require.Error(t, err)
+	_, err = fastmsgpack.NewResolverFromPaths([][]string{{"domains", "x"}, {"domains", "x"}})
+	require.Error(t, err)
+}
+
+func TestResolverOverlappingFields(t *testing.T) {
+	data := encodePerson(t)
+	decoded, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	person := decoded.(map[string]any)["person"]
+	addresses := person.(map[string]any)["addresses"]
+
+	fields := []string{"person.addresses[1].street", "person", "person.addresses", "person.name", "items[*]", "items[*].id"}
+	r, err := fastmsgpack.NewResolver(fields)
+	require.NoError(t, err)
+	sub, err := fastmsgpack.NewResolver([]string{"number"})
+	require.NoError(t, err)
+	subOffset, err := r.AddArrayResolver("person.addresses", sub)
+	require.Error(t, err, "person.addresses was already requested")
+	subOffset, err = r.AddArrayResolver("items", sub)
+	require.NoError(t, err)
+
+	wanted := []any{"Side", person, addresses, "Jan", decoded.(map[string]any)["items"], []any{10, 11}, [][]any{{nil}, {nil}}}
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+	require.Equal(t, 6, subOffset)
+
+	selected, err := r.Select(nil, data)
+	require.NoError(t, err)
+	found, err = r.Resolve(selected)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+
+	described, subs := r.Describe()
+	require.Equal(t, fields, described)
+	require.Contains(t, subs, "items")
+
+	sub, err = fastmsgpack.NewResolver([]string{"number"})
+	require.NoError(t, err)
+	r, err = fastmsgpack.NewResolver([]string{"person.addresses[1].street"})
+	require.NoError(t, err)
+	subOffset, err = r.AddArrayResolver("person.addresses", sub)
+	require.NoError(t, err)
+	found, err = r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{"Side", [][]any{{1}, {2}, {3}}}, found)
+
+	_, err = fastmsgpack.NewResolver([]string{"person.addresses", "person", "person.addresses"})
+	require.Error(t, err)
+}
+
+func TestResolveRaw(t *testing.T) {
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, []byte{0xa3, 'o', 'n', 'e'})
+	fb.SetElse([]byte{0xa4, 'e', 'l', 's', 'e'})
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name":     "Jan",
+			"greeting": fb,
+			"address":  fastmsgpack.Extension{Type: 20, Data: []byte{3}},
+			"gone":     fastmsgpack.Extension{Type: 19},
+			"tags":     []any{"a", "b"},
+		},
+	})
+	require.NoError(t, err)
+	lengthEncoded, err := fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+	injected, err := fastmsgpack.Encode(nil, map[string]any{"street": "Main"})
+	require.NoError(t, err)
+	encode := func(v any) []byte {
+		b, err := fastmsgpack.Encode(nil, v)
+		require.NoError(t, err)
+		return b
+	}
+
+	r, err := fastmsgpack.NewResolver([]string{"person.name", "person.greeting", "person.address", "person.gone", "person.tags", "person.address.street", "missing"}, fastmsgpack.WithInjection(3, injected))
+	require.NoError(t, err)
+	wanted := [][]byte{encode("Jan"), encode("else"), injected, nil, encode([]any{"a", "b"}), encode("Main"), nil}
+	for _, d := range [][]byte{data, lengthEncoded} {
+		found, err := r.ResolveRaw(d, fastmsgpack.WithFlavorSelector(1, 2), fastmsgpack.WithUnwrapLengthPrefix())
+		require.NoError(t, err)
+		require.Equal(t, wanted, found)
+	}
+
+	found, err := r.ResolveRaw(data, fastmsgpack.WithFlavorSelector(1, 1))
+	require.NoError(t, err)
+	require.Equal(t, encode("one"), found[1])
+
+	// Without WithUnwrapLengthPrefix the length-prefixed tags are returned as is.
+	found, err = r.ResolveRaw(lengthEncoded, fastmsgpack.WithFlavorSelector(1, 2))
+	require.NoError(t, err)
+	require.NotEqual(t, wanted[4], found[4])
+	decoded, err := fastmsgpack.Decode(found[4])
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "b"}, decoded)
+
+	r, err = fastmsgpack.NewResolver([]string{"person.tags[*]"})
+	require.NoError(t, err)
+	_, err = r.ResolveRaw(data)
+	require.Error(t, err)
+}
+
+func TestResolveInto(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"id":     12345,
+		"score":  1.5,
+		"ratio":  float32(0.25),
+		"active": true,
+		"name":   "Jan",
+		"none":   nil,
+		"tags":   []any{"a", "b"},
+		"items":  []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+	})
+	require.NoError(t, err)
+	r, err := fastmsgpack.NewResolver([]string{"id", "score", "ratio", "active", "name", "none", "tags", "missing", "items[*].id"})
+	require.NoError(t, err)
+
+	wanted, err := r.Resolve(data)
+	require.NoError(t, err)
+	dst := make([]any, 2)
+	found, err := r.ResolveInto(dst, data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+	found[7] = "stale"
+	found, err = r.ResolveInto(found, data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+
+	typed, err := r.ResolveTypedInto(nil, data)
+	require.NoError(t, err)
+	require.Equal(t, []fastmsgpack.TypedValue{
+		{Type: fastmsgpack.TypeInt, Int: 12345},
+		{Type: fastmsgpack.TypeFloat64, Float: 1.5},
+		{Type: fastmsgpack.TypeFloat32, Float: 0.25},
+		{Type: fastmsgpack.TypeBool, Bool: true},
+		{Type: fastmsgpack.TypeString, Str: "Jan"},
+		{Type: fastmsgpack.TypeNil},
+		{Type: fastmsgpack.TypeArray, Any: []any{"a", "b"}},
+		{},
+		{Type: fastmsgpack.TypeArray, Any: []any{1, 2}},
+	}, typed)
+
+	r, err = fastmsgpack.NewResolver([]string{"id", "score", "active", "name"})
+	require.NoError(t, err)
+	allocs := testing.AllocsPerRun(100, func() {
+		typed, err = r.ResolveTypedInto(typed, data)
+	})
+	require.NoError(t, err)
+	require.Zero(t, allocs)
+	require.Equal(t, 12345, typed[0].Int)
+}
+
+func TestResolverTypedFields(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"age":    30.0,
+			"height": 180,
+			"name":   []byte("Jan"),
+			"nick":   nil,
+			"admin":  true,
+			"born":   now,
+			"gone":   fastmsgpack.Extension{Type: 19},
+			"ratio":  0.5,
+		},
+	})
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"person.name"})
+	require.NoError(t, err)
+	age, err := r.AddInt("person.age", -1)
+	require.NoError(t, err)
+	height, err := r.AddFloat64("person.height", 0)
+	require.NoError(t, err)
+	_, err = r.AddString("person.name", "")
+	require.Error(t, err, "person.name was already requested")
+	nick, err := r.AddString("person.nick", "anonymous")
+	require.NoError(t, err)
+	admin, err := r.AddBool("person.admin", false)
+	require.NoError(t, err)
+	born, err := r.AddTime("person.born", time.Time{})
+	require.NoError(t, err)
+	gone, err := r.AddBytes("person.gone", []byte("default"))
+	require.NoError(t, err)
+	missing, err := r.AddInt("person.missing", 42)
+	require.NoError(t, err)
+	_, err = r.AddInt("person.tags[*]", 0)
+	require.Error(t, err)
+
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []byte("Jan"), found[0])
+	require.Equal(t, 30, age.Get(found))
+	require.Equal(t, 30, found[age.Index()])
+	require.Equal(t, 180.0, height.Get(found))
+	require.Equal(t, "anonymous", nick.Get(found))
+	require.True(t, nick.IsNil(found))
+	require.True(t, admin.Get(found))
+	require.True(t, now.Equal(born.Get(found)))
+	require.Equal(t, []byte("default"), gone.Get(found))
+	require.False(t, gone.IsNil(found))
+	require.Equal(t, 42, missing.Get(found))
+	require.False(t, missing.IsNil(found))
+	require.Equal(t, 42, found[missing.Index()])
+
+	found, err = r.ResolveInto(found, data)
+	require.NoError(t, err)
+	require.Equal(t, 30, age.Get(found))
+	require.Equal(t, 42, missing.Get(found))
+
+	_, err = r.AddString("person.ratio", "")
+	require.NoError(t, err)
+	_, err = r.Resolve(data)
+	require.Error(t, err)
+
+	r, err = fastmsgpack.NewResolver([]string{"person"})
+	require.NoError(t, err)
+	_, err = r.AddInt("person.ratio", 0)
+	require.NoError(t, err)
+	_, err = r.Resolve(data)
+	require.Error(t, err, "0.5 isn't integral")
+
+	sub, err := fastmsgpack.NewResolver([]string{"street"})
+	require.NoError(t, err)
+	r, err = fastmsgpack.NewResolver([]string{"person.name"})
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("person.addresses", sub)
+	require.NoError(t, err)
+	_, err = r.AddInt("person.age", 0)
+	require.NoError(t, err)
+	fields, subs := r.Describe()
+	require.Equal(t, []string{"person.name", "", "person.age"}, fields)
+	require.Equal(t, 1, subs["person.addresses"].Index)
+	_, err = sub.AddInt("number", 0)
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("person.other", sub)
+	require.Error(t, err)
+}
+
+func TestResolverPresence(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"name":  "Jan",
+		"nick":  nil,
+		"gone":  fastmsgpack.Extension{Type: 19},
+		"items": []any{map[string]any{"id": 1}},
+	})
+	require.NoError(t, err)
+	r, err := fastmsgpack.NewResolver([]string{"name", "nick", "gone", "missing", "items[*].id", "items[0].id", "person.addresses[2].street"})
+	require.NoError(t, err)
+
+	found, presence, err := r.ResolveWithPresence(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{"Jan", nil, nil, nil, []any{1}, 1, nil}, found)
+	for i, want := range []string{"found", "found", "void", "missing", "found", "found", "missing"} {
+		require.Equal(t, want == "found", presence.Found(i), i)
+		require.Equal(t, want == "void", presence.Void(i), i)
+		require.Equal(t, want == "missing", presence.Missing(i), i)
+	}
+
+	require.NoError(t, r.Require(0, 1, 4))
+	_, err = r.Resolve(data)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Require(6))
+	for _, resolve := range []func() error{
+		func() error { _, err := r.Resolve(data); return err },
+		func() error { _, err := r.ResolveInto(nil, data); return err },
+		func() error { _, err := r.ResolveTypedInto(nil, data); return err },
+	} {
+		err := resolve()
+		var mfe *fastmsgpack.MissingFieldError
+		require.ErrorAs(t, err, &mfe)
+		require.Equal(t, "person.addresses[2].street", mfe.Field)
+		require.False(t, mfe.Void)
+	}
+
+	r, err = fastmsgpack.NewResolver([]string{"gone"})
+	require.NoError(t, err)
+	require.NoError(t, r.Require(0))
+	_, err = r.Resolve(data)
+	require.EqualError(t, err, "fastmsgpack: required field gone is void")
+	require.Error(t, r.Require(1))
+}
+
+func TestResolverFilteredArrayResolver(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"addresses": []any{
+				map[string]any{"type": "work", "street": "Main"},
+				map[string]any{"type": "home", "street": "Side"},
+				fastmsgpack.Extension{Type: 19},
+				map[string]any{"type": "home", "street": "Back"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	sub, err := fastmsgpack.NewResolver([]string{"type", "street"})
+	require.NoError(t, err)
+	r, err := fastmsgpack.NewResolver(nil)
+	require.NoError(t, err)
+	var calls int
+	offset, err := r.AddFilteredArrayResolver("person.addresses", sub, func(found []any) bool {
+		calls++
+		return found[0] == "home"
+	})
+	require.NoError(t, err)
+
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, [][]any{{"home", "Side"}, {"home", "Back"}}, found[offset])
+	require.Equal(t, 3, calls)
+
+	selected, err := r.Select(nil, data)
+	require.NoError(t, err)
+	decoded, err := fastmsgpack.Decode(selected)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"person": map[string]any{
+			"addresses": []any{
+				map[string]any{"type": "home", "street": "Side"},
+				map[string]any{"type": "home", "street": "Back"},
+			},
+		},
+	}, decoded)
+
+	// Only resolving the first field of each element must still move on to the next element correctly.
+	typeOnly, err := fastmsgpack.NewResolver([]string{"type"})
+	require.NoError(t, err)
+	r, err = fastmsgpack.NewResolver(nil)
+	require.NoError(t, err)
+	_, err = r.AddFilteredArrayResolver("person.addresses", typeOnly, func(found []any) bool {
+		return found[0] == "home"
+	})
+	require.NoError(t, err)
+	selected, err = r.Select(nil, data)
+	require.NoError(t, err)
+	decoded, err = fastmsgpack.Decode(selected)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"person": map[string]any{
+			"addresses": []any{
+				map[string]any{"type": "home"},
+				map[string]any{"type": "home"},
+			},
+		},
+	}, decoded)
+}
+
+func TestResolverFilteredArrayResolverExtensions(t *testing.T) {
+	addresses, err := fastmsgpack.Encode(nil, []any{
+		map[string]any{"type": "work", "street": "Main"},
+		map[string]any{"type": "home", "street": "Side"},
+	})
+	require.NoError(t, err)
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, addresses)
+	fb.SetElse([]byte{0x90})
+	want := map[string]any{
+		"person": map[string]any{
+			"addresses": []any{map[string]any{"type": "home", "street": "Side"}},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		addresses any
+		opt       fastmsgpack.DecodeOption
+	}{
+		"injection": {fastmsgpack.Extension{Type: 20, Data: []byte{1}}, fastmsgpack.WithInjection(1, addresses)},
+		"flavor":    {fb, fastmsgpack.WithFlavorSelector(1, 1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := fastmsgpack.Encode(nil, map[string]any{"person": map[string]any{"addresses": tc.addresses}})
+			require.NoError(t, err)
+			sub, err := fastmsgpack.NewResolver([]string{"type", "street"})
+			require.NoError(t, err)
+			r, err := fastmsgpack.NewResolver(nil, tc.opt)
+			require.NoError(t, err)
+			_, err = r.AddFilteredArrayResolver("person.addresses", sub, func(found []any) bool {
+				return found[0] == "home"
+			})
+			require.NoError(t, err)
+
+			selected, err := r.Select(nil, data)
+			require.NoError(t, err)
+			decoded, err := fastmsgpack.Decode(selected, tc.opt)
+			require.NoError(t, err)
+			require.Equal(t, want, decoded)
+		})
+	}
+}
+
+func TestResolverArrayResolverShapes(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"tags":   []any{"a", "b"},
+		"matrix": []any{[]any{1, 2}, []any{3, 4}},
+		"mixed":  []any{"x", map[string]any{"id": 1}, []any{5}, nil},
+	})
+	require.NoError(t, err)
+
+	self, err := fastmsgpack.NewResolver([]string{""})
+	require.NoError(t, err)
+	first, err := fastmsgpack.NewResolver([]string{"[0]", "[-1]"})
+	require.NoError(t, err)
+	row, err := fastmsgpack.NewResolver([]string{""})
+	require.NoError(t, err)
+	nested, err := fastmsgpack.NewResolver(nil)
+	require.NoError(t, err)
+	_, err = nested.AddArrayResolver("", row)
+	require.NoError(t, err)
+	mixed, err := fastmsgpack.NewResolver([]string{"", "id"})
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver(nil)
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("tags", self)
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("matrix", first)
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("mixed", mixed)
+	require.NoError(t, err)
+
+	wanted := []any{
+		[][]any{{"a"}, {"b"}},
+		[][]any{{1, 2}, {3, 4}},
+		[][]any{{"x", nil}, {map[string]any{"id": 1}, 1}, {[]any{5}, nil}, {nil, nil}},
+	}
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+
+	selected, err := r.Select(nil, data)
+	require.NoError(t, err)
+	found, err = r.Resolve(selected)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+
+	fields, subs := r.Describe()
+	require.Empty(t, fields)
+	require.Equal(t, []string{""}, subs["tags"].Fields)
+	require.Equal(t, []string{"[0]", "[-1]"}, subs["matrix"].Fields)
+	require.Equal(t, []string{"", "id"}, subs["mixed"].Fields)
+
+	r, err = fastmsgpack.NewResolver(nil)
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("matrix", nested)
+	require.NoError(t, err)
+	found, err = r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, []any{[][]any{{[][]any{{1}, {2}}}, {[][]any{{3}, {4}}}}}, found)
+
+	// The root of the data can be an array too.
+	r, err = fastmsgpack.NewResolver([]string{"[1]", ""})
+	require.NoError(t, err)
+	list, err := fastmsgpack.Encode(nil, []any{"a", "b"})
+	require.NoError(t, err)
+	found, err = r.Resolve(list)
+	require.NoError(t, err)
+	require.Equal(t, []any{"b", []any{"a", "b"}}, found)
+}
+
+func TestSelectExcept(t *testing.T) {
+	encode := func(v any) []byte {
+		b, err := fastmsgpack.Encode(nil, v)
+		require.NoError(t, err)
+		return b
+	}
+	greeting := fastmsgpack.NewFlavorBuilder(1)
+	greeting.AddCase(1, encode(map[string]any{"text": "hoi", "secret": "x"}))
+	greeting.SetElse(encode(map[string]any{"text": "hello", "secret": "y"}))
+	motto := fastmsgpack.NewFlavorBuilder(1)
+	motto.AddCase(1, encode("ja"))
+	motto.SetElse(encode("yes"))
+	raw := encode(map[string]any{
+		"user": map[string]any{
+			"name":     "Jan",
+			"password": "hunter2",
+			"tokens":   []any{map[string]any{"id": 1, "secret": "a"}, map[string]any{"id": 2, "secret": "b"}},
+			"greeting": greeting,
+			"motto":    motto,
+			"settings": map[string]any{"theme": "dark"},
+		},
+		"items": []any{"a", "b", "c"},
+	})
+	data, err := fastmsgpack.LengthEncode(nil, raw)
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"user.password", "user.tokens[*].secret", "user.greeting.secret", "items[0]", "missing.x"})
+	require.NoError(t, err)
+	for _, d := range [][]byte{raw, data} {
+		out, err := r.SelectExcept([]byte{0xff}, d)
+		require.NoError(t, err)
+		require.Equal(t, byte(0xff), out[0])
+		require.NoError(t, fastmsgpack.Validate(out[1:]))
+		for flavor, text := range map[uint][2]string{1: {"hoi", "ja"}, 2: {"hello", "yes"}} {
+			decoded, err := fastmsgpack.Decode(out[1:], fastmsgpack.WithFlavorSelector(1, flavor))
+			require.NoError(t, err)
+			require.Equal(t, map[string]any{
+				"user": map[string]any{
+					"name":     "Jan",
+					"tokens":   []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+					"greeting": map[string]any{"text": text[0]},
+					"motto":    text[1],
+					"settings": map[string]any{"theme": "dark"},
+				},
+				"items": []any{"b", "c"},
+			}, decoded)
+		}
+	}
+
+	// Untouched values are copied as is, including their length prefix and flavor.
+	out, err := r.SelectExcept(nil, data)
+	require.NoError(t, err)
+	settings, err := fastmsgpack.LengthEncode(nil, encode(map[string]any{"theme": "dark"}))
+	require.NoError(t, err)
+	require.Contains(t, string(out), string(settings))
+	mottoBytes, err := motto.AppendMsgpack(nil)
+	require.NoError(t, err)
+	require.Contains(t, string(out), string(mottoBytes))
+
+	// Nothing to exclude gives the same bytes.
+	r, err = fastmsgpack.NewResolver([]string{"user.nothing", "items[5]"})
+	require.NoError(t, err)
+	out, err = r.SelectExcept(nil, data)
+	require.NoError(t, err)
+	require.Equal(t, data, out)
+
+	_, err = r.SelectExcept(nil, data[:len(data)-1])
+	require.Error(t, err)
+}
+
+func TestSelectExceptOverlappingElements(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"items":  []any{map[string]any{"a": 1, "b": 2}, map[string]any{"a": 3, "b": 4}},
+		"matrix": []any{[]any{map[string]any{"x": 1, "y": 2}}, []any{map[string]any{"x": 3, "y": 4}}},
+	})
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"items[0].a", "items[*].b", "matrix[0][0].y"})
+	require.NoError(t, err)
+	sub, err := fastmsgpack.NewResolver([]string{"x"})
+	require.NoError(t, err)
+	_, err = r.AddArrayResolver("matrix[*]", sub)
+	require.NoError(t, err)
+	out, err := r.SelectExcept(nil, data)
+	require.NoError(t, err)
+	decoded, err := fastmsgpack.Decode(out)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"items":  []any{map[string]any{}, map[string]any{"a": 3}},
+		"matrix": []any{[]any{map[string]any{}}, []any{map[string]any{"y": 4}}},
+	}, decoded)
+
+	// An element can't be both a map and an array, so rather than dropping it entirely, SelectExcept refuses.
+	r, err = fastmsgpack.NewResolver([]string{"items[0].a", "items[*][1]"})
+	require.NoError(t, err)
+	_, err = r.SelectExcept(nil, data)
+	require.ErrorContains(t, err, "conflicting fields")
+}
+
+func TestProjection(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"properties": map[string]any{"firstName": "Jan", "lastName": "Jansen", "age": 42},
+			"addresses":  []any{map[string]any{"street": "Main"}},
+			"gone":       fastmsgpack.Extension{Type: 19},
+		},
+	})
+	require.NoError(t, err)
+
+	p, err := fastmsgpack.NewProjection([]fastmsgpack.ProjectedField{
+		{From: "person.properties.firstName", To: "first_name"},
+		{From: "person.properties.lastName", To: "name.last"},
+		{From: "person.properties.firstName", To: "name.first"},
+		{From: "person.addresses[0].street", To: `address.street\.name`},
+		{From: "person.properties", To: "properties"},
+		{From: "person.gone", To: "gone.x"},
+		{From: "missing", To: "missing"},
+	})
+	require.NoError(t, err)
+	out, err := p.Project([]byte{0xff}, data)
+	require.NoError(t, err)
+	require.Equal(t, byte(0xff), out[0])
+	decoded, err := fastmsgpack.Decode(out[1:])
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"first_name": "Jan",
+		"name":       map[string]any{"last": "Jansen", "first": "Jan"},
+		"address":    map[string]any{"street.name": "Main"},
+		"properties": map[string]any{"firstName": "Jan", "lastName": "Jansen", "age": 42},
+	}, decoded)
+
+	for _, fields := range [][]fastmsgpack.ProjectedField{
+		{{From: "a", To: "x"}, {From: "b", To: "x"}},
+		{{From: "a", To: "x"}, {From: "b", To: "x.y"}},
+		{{From: "a", To: "x.y"}, {From: "b", To: "x"}},
+		{{From: "a", To: "x[0]"}},
+		{{From: "a", To: ""}},
+		{{From: "a[*]", To: "x"}},
+		{{From: "a[", To: "x"}},
+	} {
+		_, err := fastmsgpack.NewProjection(fields)
+		require.Error(t, err, fields)
+	}
+}
+
+func TestSelectWithOptions(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"person"})
+	raw, err := fastmsgpack.EncodeOptions{Dict: map[string]int{"person": 0}}.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name":      "Jan",
+			"addresses": []any{map[string]any{"street": "Main", "number": 1}},
+			"age":       42,
+		},
+		"other": 1,
+	})
+	require.NoError(t, err)
+	data, err := fastmsgpack.LengthEncode(nil, raw)
+	require.NoError(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"person.name", "person.addresses[0].street", "person.age"}, fastmsgpack.WithDict(dict))
+	require.NoError(t, err)
+	selected, err := r.Select(nil, data)
+	require.NoError(t, err)
+	wanted, err := fastmsgpack.Decode(selected, fastmsgpack.WithDict(dict))
+	require.NoError(t, err)
+
+	compact, err := r.SelectWithOptions(nil, data, fastmsgpack.SelectOptions{CompactHeaders: true})
+	require.NoError(t, err)
+	require.Less(t, len(compact), len(selected))
+	decoded, err := fastmsgpack.Decode(compact, fastmsgpack.WithDict(dict))
+	require.NoError(t, err)
+	require.Equal(t, wanted, decoded)
+
+	prefixed, err := r.SelectWithOptions([]byte{0xff}, data, fastmsgpack.SelectOptions{CompactHeaders: true, LengthPrefix: true})
+	require.NoError(t, err)
+	lengthEncoded, err := fastmsgpack.LengthEncode([]byte{0xff}, compact)
+	require.NoError(t, err)
+	require.Equal(t, lengthEncoded, prefixed)
+	require.NoError(t, fastmsgpack.Validate(prefixed[1:], fastmsgpack.WithDict(dict)))
+
+	// Keys are re-interned with the new dict, including the ones that were interned with the old one.
+	newDict := fastmsgpack.MakeDict([]string{"name", "street"})
+	reinterned, err := r.SelectWithOptions(nil, data, fastmsgpack.SelectOptions{KeyEncoding: &fastmsgpack.EncodeOptions{Dict: map[string]int{"name": 0, "street": 1}}})
+	require.NoError(t, err)
+	require.Less(t, len(reinterned), len(selected))
+	decoded, err = fastmsgpack.Decode(reinterned, fastmsgpack.WithDict(newDict))
+	require.NoError(t, err)
+	require.Equal(t, wanted, decoded)
+
+	unchanged, err := r.SelectWithOptions(nil, data, fastmsgpack.SelectOptions{})
+	require.NoError(t, err)
+	require.Equal(t, selected, unchanged)
+}
+
+func TestResolverCompileForDict(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"person", "name", "addresses", "street", "unused", "name"})
+	eo := fastmsgpack.EncodeOptions{Dict: map[string]int{"person": 0, "name": 5, "addresses": 2, "street": 3, "unused": 4}}
+	data, err := eo.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name":      "Jan",
+			"unused":    1,
+			"age":       42,
+			"addresses": []any{map[string]any{"street": "Main", "number": 1}, map[string]any{"street": "Side"}},
+		},
+	})
+	require.NoError(t, err)
+
+	fields := []string{"person.name", "person.age", "person.addresses[*].street", "person.addresses[1]", "person.missing"}
+	build := func() *fastmsgpack.Resolver {
+		r, err := fastmsgpack.NewResolver(fields, fastmsgpack.WithDict(dict))
+		require.NoError(t, err)
+		sub, err := fastmsgpack.NewResolver([]string{"street", "number"})
+		require.NoError(t, err)
+		_, err = r.AddArrayResolver("person.addresses", sub)
+		require.NoError(t, err)
+		return r
+	}
+	wanted, err := build().Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, "Jan", wanted[0])
+	require.Equal(t, 42, wanted[1])
+
+	r := build()
+	r.CompileForDict(dict)
+	found, err := r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+	// Another dict with the same strings isn't compiled for, but works too.
+	found, err = r.Resolve(data, fastmsgpack.WithDict(fastmsgpack.MakeDict(dict.Strings)))
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+
+	// Keys that aren't interned are still matched.
+	plain, err := fastmsgpack.Encode(nil, map[string]any{"person": map[string]any{"name": "Piet"}})
+	require.NoError(t, err)
+	found, err = r.Resolve(plain)
+	require.NoError(t, err)
+	require.Equal(t, "Piet", found[0])
+
+	// A nil dict undoes the compilation.
+	r.CompileForDict(nil)
+	found, err = r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+	r.CompileForDict(dict)
+
+	// Adding fields undoes the compilation, but the results stay correct.
+	_, err = r.AddString("person.addresses[0].street", "")
+	require.NoError(t, err)
+	found, err = r.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, append(wanted, "Main"), found)
+}
+
+func TestResolverDescription(t *testing.T) {
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, []byte{0xa3, 'o', 'n', 'e'})
+	fb.SetElse([]byte{0xa4, 'e', 'l', 's', 'e'})
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"person": map[string]any{
+			"name":     "Jan",
+			"age":      42.0,
+			"greeting": fb,
+			"addresses": []any{
+				map[string]any{"type": "home", "street": "Main", "lines": []any{map[string]any{"text": "a"}}},
+				map[string]any{"type": "work", "street": "Side"},
+			},
+		},
+		"items": []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+	})
+	require.NoError(t, err)
+	isHome := func(found []any) bool { return found[0] == "home" }
+
+	r, err := fastmsgpack.NewResolver([]string{"person.name", "items[*].id"}, fastmsgpack.WithFlavorSelector(1, 1), fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 10}))
+	require.NoError(t, err)
+	sub, err := fastmsgpack.NewResolver([]string{"type", "street"})
+	require.NoError(t, err)
+	lines, err := fastmsgpack.NewResolver([]string{"text"})
+	require.NoError(t, err)
+	_, err = sub.AddArrayResolver("lines", lines)
+	require.NoError(t, err)
+	_, err = r.AddNamedFilteredArrayResolver("person.addresses", sub, "home", isHome)
+	require.NoError(t, err)
+	age, err := r.AddInt("person.age", -1)
+	require.NoError(t, err)
+	_, err = r.AddTime("person.born", time.Unix(100, 0))
+	require.NoError(t, err)
+	_, err = r.AddString("person.greeting", "")
+	require.NoError(t, err)
+	require.NoError(t, r.Require(0, age.Index()))
+	wanted, err := r.Resolve(data)
+	require.NoError(t, err)
+
+	d, err := r.Description()
+	require.NoError(t, err)
+	encoded, err := fastmsgpack.Encode(nil, d)
+	require.NoError(t, err)
+	var decoded fastmsgpack.ResolverDescription
+	require.NoError(t, fastmsgpack.Unmarshal(encoded, &decoded))
+	require.Equal(t, d, decoded)
+
+	copied, err := fastmsgpack.NewResolverFromDescription(decoded, map[string]func([]any) bool{"home": isHome})
+	require.NoError(t, err)
+	found, err := copied.Resolve(data)
+	require.NoError(t, err)
+	require.Equal(t, wanted, found)
+	require.Equal(t, "one", found[5])
+	copiedDescription, err := copied.Description()
+	require.NoError(t, err)
+	require.Equal(t, d, copiedDescription)
+
+	_, err = copied.Resolve([]byte{0x80})
+	var mfe *fastmsgpack.MissingFieldError
+	require.ErrorAs(t, err, &mfe)
+
+	_, err = fastmsgpack.NewResolverFromDescription(decoded, nil)
+	require.Error(t, err)
+	_, err = r.AddFilteredArrayResolver("items", sub, isHome)
+	require.NoError(t, err)
+	_, err = r.Description()
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	var data []byte
+	values := []any{"first", 2, map[string]any{"three": []any{3, "3"}}, string(bytes.Repeat([]byte{'x'}, 10000)), nil}
+	for _, v := range values {
+		var err error
+		data, err = fastmsgpack.Encode(data, v)
+		require.NoError(t, err)
+	}
+
+	sd := fastmsgpack.NewStreamDecoder(iotest.OneByteReader(bytes.NewReader(data)))
+	for _, want := range values {
+		d, err := sd.Next()
+		require.NoError(t, err)
+		got, err := d.DecodeValue()
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := sd.Next()
+	require.Equal(t, io.EOF, err)
+
+	sd = fastmsgpack.NewStreamDecoder(bytes.NewReader(data))
+	var offset int
+	for range values {
+		b, err := sd.NextRaw()
+		require.NoError(t, err)
+		n, err := fastmsgpack.Size(data[offset:])
+		require.NoError(t, err)
+		require.Equal(t, data[offset:offset+n], b)
+		offset += n
+	}
+	_, err = sd.NextRaw()
+	require.Equal(t, io.EOF, err)
+
+	sd = fastmsgpack.NewStreamDecoder(bytes.NewReader(data[:len(data)-2]))
+	for range values[:3] {
+		_, err := sd.NextRaw()
+		require.NoError(t, err)
+	}
+	_, err = sd.NextRaw()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
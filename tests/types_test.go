@@ -198,6 +198,49 @@ func TestUint64(t *testing.T) {
 	}
 }
 
+func TestUint64Fidelity(t *testing.T) {
+	const big = uint64(math.MaxUint64 - 1)
+	data, err := fastmsgpack.Encode(nil, big)
+	require.NoError(t, err)
+
+	out, err := fastmsgpack.Decode(data, fastmsgpack.WithUint64())
+	require.NoError(t, err)
+	require.Equal(t, big, out)
+	out, err = fastmsgpack.Decode([]byte{0x7f}, fastmsgpack.WithUint64())
+	require.NoError(t, err)
+	require.Equal(t, 127, out)
+	_, err = fastmsgpack.Decode(data, fastmsgpack.WithIntOverflowError())
+	require.ErrorIs(t, err, fastmsgpack.ErrIntOverflow)
+
+	d := fastmsgpack.NewDecoder(data)
+	u, err := d.DecodeUint()
+	require.NoError(t, err)
+	require.Equal(t, big, u)
+
+	d = fastmsgpack.NewDecoder(data, fastmsgpack.WithIntOverflowError())
+	_, err = d.DecodeInt()
+	require.ErrorIs(t, err, fastmsgpack.ErrIntOverflow)
+
+	neg, err := fastmsgpack.Encode(nil, -5)
+	require.NoError(t, err)
+	_, err = fastmsgpack.NewDecoder(neg).DecodeUint()
+	require.Error(t, err)
+
+	r, err := fastmsgpack.NewResolver([]string{"id"}, fastmsgpack.WithUint64())
+	require.NoError(t, err)
+	m, err := fastmsgpack.Encode(nil, map[string]any{"id": big})
+	require.NoError(t, err)
+	found, err := r.Resolve(m)
+	require.NoError(t, err)
+	require.Equal(t, []any{big}, found)
+
+	var s struct {
+		ID uint64 `msgpack:"id"`
+	}
+	require.NoError(t, fastmsgpack.Unmarshal(m, &s))
+	require.Equal(t, big, s.ID)
+}
+
 func TestInt64(t *testing.T) {
 	tests := []struct {
 		in     int64
@@ -0,0 +1,142 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+type unmarshalAddress struct {
+	Street string `msgpack:"street"`
+	Number int    `msgpack:"number,omitempty"`
+}
+
+type unmarshalBase struct {
+	ID uint32 `msgpack:"id"`
+}
+
+type unmarshalPerson struct {
+	unmarshalBase
+	Name      string             `msgpack:"name"`
+	Age       *int               `msgpack:"age"`
+	Tags      []string           `msgpack:"tags"`
+	Addresses []unmarshalAddress `msgpack:"addresses"`
+	Extra     map[string]any     `msgpack:"extra"`
+	Scores    map[string]float64 `msgpack:"scores"`
+	Born      time.Time          `msgpack:"born"`
+	Ignored   string             `msgpack:"-"`
+	Untagged  bool
+	Nested    *unmarshalAddress   `msgpack:"nested"`
+	Pairs     [2]int              `msgpack:"pairs"`
+	Lookup    map[string][]string `msgpack:"lookup"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	born := time.Unix(1700000000, 0)
+	data, err := fastmsgpack.Encode(nil, map[string]any{
+		"id":   17,
+		"name": "Jan",
+		"age":  42,
+		"tags": []any{"a", "b"},
+		"addresses": []any{
+			map[string]any{"street": "Main Street", "number": 1},
+			map[string]any{"street": "Second Street"},
+		},
+		"extra":    map[string]any{"x": 1},
+		"scores":   map[string]any{"math": 7.5, "art": 8},
+		"born":     born,
+		"-":        "nope",
+		"Ignored":  "nope",
+		"Untagged": true,
+		"unknown":  []any{1, 2, 3},
+		"nested":   map[string]any{"street": "Nested Street"},
+		"pairs":    []any{1, 2, 3},
+		"lookup":   map[string]any{"k": []any{"v"}},
+	})
+	require.NoError(t, err)
+
+	var got unmarshalPerson
+	require.NoError(t, fastmsgpack.Unmarshal(data, &got))
+	age := 42
+	want := unmarshalPerson{
+		unmarshalBase: unmarshalBase{ID: 17},
+		Name:          "Jan",
+		Age:           &age,
+		Tags:          []string{"a", "b"},
+		Addresses:     []unmarshalAddress{{"Main Street", 1}, {"Second Street", 0}},
+		Extra:         map[string]any{"x": 1},
+		Scores:        map[string]float64{"math": 7.5, "art": 8},
+		Born:          born,
+		Untagged:      true,
+		Nested:        &unmarshalAddress{Street: "Nested Street"},
+		Pairs:         [2]int{1, 2},
+		Lookup:        map[string][]string{"k": {"v"}},
+	}
+	require.Equal(t, want, got)
+}
+
+func TestUnmarshalExtensions(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"street", "number"})
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, []byte{0xa3, 'o', 'n', 'e'})
+	fb.SetElse([]byte{0xa4, 'e', 'l', 's', 'e'})
+
+	data, err := fastmsgpack.EncodeOptions{Dict: map[string]int{"street": 0, "number": 1}}.Encode(nil, []any{
+		map[string]any{
+			"street": fastmsgpack.Extension{Type: 20, Data: []byte{3}},
+			"number": fastmsgpack.Extension{Type: 19},
+		},
+		map[string]any{
+			"street": fb,
+		},
+		fastmsgpack.Extension{Type: 19},
+	})
+	require.NoError(t, err)
+	lengthEncoded, err := fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+
+	for _, d := range [][]byte{data, lengthEncoded} {
+		got := []unmarshalAddress{{Number: 5}}
+		err = fastmsgpack.Unmarshal(d, &got, fastmsgpack.WithDict(dict), fastmsgpack.WithFlavorSelector(1, 1), fastmsgpack.WithInjection(3, []byte{0xa8, 'i', 'n', 'j', 'e', 'c', 't', 'e', 'd'}))
+		require.NoError(t, err)
+		require.Equal(t, []unmarshalAddress{{Street: "injected"}, {Street: "one"}}, got)
+	}
+}
+
+type unmarshalBlob []byte
+
+func TestUnmarshalNamedBytes(t *testing.T) {
+	in := map[string]unmarshalBlob{"blob": {1, 2, 3}}
+	data, err := fastmsgpack.Encode(nil, in)
+	require.NoError(t, err)
+	v, err := fastmsgpack.Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"blob": []byte{1, 2, 3}}, v)
+
+	var out map[string]unmarshalBlob
+	require.NoError(t, fastmsgpack.Unmarshal(data, &out))
+	require.Equal(t, in, out)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	data, err := fastmsgpack.Encode(nil, 300)
+	require.NoError(t, err)
+
+	var i8 int8
+	require.Error(t, fastmsgpack.Unmarshal(data, &i8))
+	var s string
+	require.Error(t, fastmsgpack.Unmarshal(data, &s))
+	require.Error(t, fastmsgpack.Unmarshal(data, s))
+
+	// A uint 64 above math.MaxInt64 must not wrap around to a negative number.
+	data, err = fastmsgpack.Encode(nil, uint64(1<<63+5))
+	require.NoError(t, err)
+	var i64 int64
+	err = fastmsgpack.Unmarshal(data, &i64)
+	require.ErrorContains(t, err, "9223372036854775813 overflows int64")
+	var u64 uint64
+	require.NoError(t, fastmsgpack.Unmarshal(data, &u64))
+	require.Equal(t, uint64(1<<63+5), u64)
+}
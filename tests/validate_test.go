@@ -0,0 +1,87 @@
+package msgpack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexon/fastmsgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	dict := fastmsgpack.MakeDict([]string{"street", "number"})
+	fb := fastmsgpack.NewFlavorBuilder(1)
+	fb.AddCase(1, []byte{0xa3, 'o', 'n', 'e'})
+	fb.AddCase(2, []byte{0x92, 0x01, 0x02})
+	fb.SetElse([]byte{0xa4, 'e', 'l', 's', 'e'})
+
+	data, err := fastmsgpack.EncodeOptions{Dict: map[string]int{"street": 0, "number": 1}}.Encode(nil, []any{
+		map[string]any{
+			"street": fastmsgpack.Extension{Type: 20, Data: []byte{3}},
+			"number": fastmsgpack.Extension{Type: 19},
+		},
+		map[string]any{
+			"street": fb,
+			"born":   time.Unix(1700000000, 5),
+		},
+		[]any{1.5, float32(2.5), -3, uint64(1 << 63), nil, true, []byte("bin")},
+	})
+	require.NoError(t, err)
+	lengthEncoded, err := fastmsgpack.LengthEncode(nil, data)
+	require.NoError(t, err)
+
+	for _, d := range [][]byte{data, lengthEncoded} {
+		require.NoError(t, fastmsgpack.Validate(d))
+		require.NoError(t, fastmsgpack.Validate(d, fastmsgpack.WithDict(dict), fastmsgpack.WithValidateUTF8()))
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = fastmsgpack.Validate(d)
+		})
+		require.Zero(t, allocs)
+
+		for i := 0; len(d) > i; i++ {
+			err := fastmsgpack.Validate(d[:i])
+			require.Error(t, err, "truncated at %d", i)
+			var de *fastmsgpack.DecodeError
+			require.ErrorAs(t, err, &de)
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	extension := func(typ int8, data ...byte) []byte {
+		b, err := fastmsgpack.Extension{Type: typ, Data: data}.AppendMsgpack(nil)
+		require.NoError(t, err)
+		return b
+	}
+	dict := fastmsgpack.MakeDict([]string{"street", "number"})
+
+	tests := []struct {
+		name   string
+		data   []byte
+		opts   []fastmsgpack.DecodeOption
+		offset int
+	}{
+		{"reserved byte", []byte{0x93, 0x01, 0x02, 0xc1}, nil, 3},
+		{"trailing data", []byte{0x01, 0x02}, nil, 1},
+		{"invalid utf-8", []byte{0x91, 0xa2, 0xff, 0xfe}, []fastmsgpack.DecodeOption{fastmsgpack.WithValidateUTF8()}, 1},
+		{"timestamp length", append([]byte{0x92, 0xc0}, extension(-1, 1, 2, 3, 4, 5)...), nil, 2},
+		{"length-prefix mismatch", append([]byte{0x81, 0xa1, 'a'}, extension(17, 0x01, 0x02)...), nil, 3},
+		{"length-prefixed content", extension(17, 0x92, 0x01), nil, 2},
+		{"dict index", append([]byte{0x91}, extension(-128, 5)...), []fastmsgpack.DecodeOption{fastmsgpack.WithDict(dict)}, 1},
+		{"flavor jump", extension(18, 1, 2, 1, 50), nil, 0},
+		{"flavor table", extension(18, 1, 4, 1), nil, 0},
+		{"flavor case", extension(18, 1, 2, 1, 4, 0xc1), nil, 7},
+		{"limits", []byte{0x91, 0x91, 0x90}, []fastmsgpack.DecodeOption{fastmsgpack.WithLimits(fastmsgpack.Limits{MaxDepth: 2})}, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fastmsgpack.Validate(tc.data, tc.opts...)
+			var de *fastmsgpack.DecodeError
+			require.ErrorAs(t, err, &de)
+			require.Equal(t, tc.offset, de.Offset, err.Error())
+		})
+	}
+
+	require.NoError(t, fastmsgpack.Validate([]byte{0x91, 0xa2, 0xff, 0xfe}))
+	require.NoError(t, fastmsgpack.Validate(append([]byte{0x91}, extension(-128, 5)...)))
+}
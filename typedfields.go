@@ -0,0 +1,189 @@
+package fastmsgpack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Field is a handle to a field registered with one of the typed Add methods, like AddInt.
+// Resolve converts the value of the field to T, or uses the default if the field is missing.
+type Field[T any] struct {
+	index int
+	def   T
+}
+
+// Index returns the offset of the field in the return value of Resolve.
+func (f Field[T]) Index() int {
+	return f.index
+}
+
+// Get returns the value of the field from the return value of Resolve. It returns the default if the field was missing or nil.
+func (f Field[T]) Get(found []any) T {
+	if v, ok := found[f.index].(T); ok {
+		return v
+	}
+	return f.def
+}
+
+// IsNil returns whether the field was present with an explicit nil value. Missing fields get the default value instead, so they aren't nil.
+func (f Field[T]) IsNil(found []any) bool {
+	return found[f.index] == nil
+}
+
+type typedField struct {
+	index int
+	field string
+	def   any
+	// kind is the type of the field as used in FieldDescription.Type.
+	kind    string
+	convert func(v any) (any, bool)
+}
+
+// missingField is put in the result for typed fields before resolving, to tell missing fields apart from nil.
+type missingField struct{}
+
+// AddInt adds a field that is converted to an int. Floats are accepted if they're integral.
+// It returns a handle to get the value from the return value of Resolve. Missing fields get the given default.
+// Like AddArrayResolver, it can not be called concurrently with itself or Resolve.
+func (r *Resolver) AddInt(field string, def int) (Field[int], error) {
+	return addTypedField(r, field, def, "int", convertInt)
+}
+
+// AddFloat64 adds a field that is converted to a float64. Integers are accepted too. See AddInt.
+func (r *Resolver) AddFloat64(field string, def float64) (Field[float64], error) {
+	return addTypedField(r, field, def, "float64", convertFloat64)
+}
+
+// AddString adds a field that is converted to a string. Binary data is accepted too. See AddInt.
+func (r *Resolver) AddString(field string, def string) (Field[string], error) {
+	return addTypedField(r, field, def, "string", convertString)
+}
+
+// AddBytes adds a field that is converted to a []byte. Strings are accepted too. See AddInt.
+func (r *Resolver) AddBytes(field string, def []byte) (Field[[]byte], error) {
+	return addTypedField(r, field, def, "bytes", convertBytes)
+}
+
+// AddBool adds a bool field. See AddInt.
+func (r *Resolver) AddBool(field string, def bool) (Field[bool], error) {
+	return addTypedField(r, field, def, "bool", convertExact[bool])
+}
+
+// AddTime adds a timestamp field. See AddInt.
+func (r *Resolver) AddTime(field string, def time.Time) (Field[time.Time], error) {
+	return addTypedField(r, field, def, "time", convertExact[time.Time])
+}
+
+func addTypedField[T any](r *Resolver, field string, def T, kind string, convert func(v any) (T, bool)) (Field[T], error) {
+	path, err := parsePath(field)
+	if err != nil {
+		return Field[T]{}, err
+	}
+	for _, seg := range path {
+		if seg.wildcard {
+			return Field[T]{}, errors.New("NewResolver: typed fields can't contain wildcards: " + field)
+		}
+	}
+	dst := r.numFields
+	if err := r.addPath(path, field, dst); err != nil {
+		return Field[T]{}, err
+	}
+	r.numFields++
+	r.typedFields = append(r.typedFields, typedField{
+		index: dst,
+		field: field,
+		def:   def,
+		kind:  kind,
+		convert: func(v any) (any, bool) {
+			return convert(v)
+		},
+	})
+	return Field[T]{dst, def}, nil
+}
+
+// markTypedFields marks the typed fields as missing, so convertTypedFields can tell them apart from nil values.
+func (r *Resolver) markTypedFields(result []any) {
+	for _, tf := range r.typedFields {
+		result[tf.index] = missingField{}
+	}
+}
+
+// convertTypedFields converts the values of the typed fields and fills in the defaults for missing fields.
+func (r *Resolver) convertTypedFields(result []any) error {
+	for _, tf := range r.typedFields {
+		switch v := result[tf.index].(type) {
+		case missingField:
+			result[tf.index] = tf.def
+		case nil:
+		default:
+			c, ok := tf.convert(v)
+			if !ok {
+				return fmt.Errorf("fastmsgpack: can't convert %T to %T for field %s", v, tf.def, tf.field)
+			}
+			result[tf.index] = c
+		}
+	}
+	return nil
+}
+
+func convertExact[T any](v any) (T, bool) {
+	c, ok := v.(T)
+	return c, ok
+}
+
+func convertInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case uint64:
+		return int(v), v <= math.MaxInt
+	case float32:
+		return floatToInt(float64(v))
+	case float64:
+		return floatToInt(v)
+	}
+	return 0, false
+}
+
+func floatToInt(f float64) (int, bool) {
+	if f != math.Trunc(f) || f < math.MinInt || f >= math.MaxInt {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func convertFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func convertString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+func convertBytes(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	return nil, false
+}
@@ -0,0 +1,358 @@
+package fastmsgpack
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	extensionType = reflect.TypeOf(Extension{})
+)
+
+// Unmarshal decodes the msgpack data into the value pointed to by v.
+// v can point to structs, slices, arrays, maps, pointers and builtin types. Struct fields can be renamed with tags like `msgpack:"name"` and ignored with `msgpack:"-"`.
+// Structs can be decoded from maps or from arrays (as written for structs with as_array).
+// Types that implement DecodeMsgpack(*Decoder) error (see msgpackgen) or UnmarshalMsgpack([]byte) error decode themselves.
+// Any []byte and string in v might point into memory from the given data. Don't modify the input data until you're done with v.
+func Unmarshal(data []byte, v any, opts ...DecodeOption) error {
+	d := NewDecoder(data, opts...)
+	if err := d.opt.CheckBytes(data); err != nil {
+		return decodeErrorAt(err, 0, "")
+	}
+	return d.Unmarshal(v)
+}
+
+// Unmarshal decodes the next value in the msgpack data into the value pointed to by v. See the package level Unmarshal.
+func (d *Decoder) Unmarshal(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("fastmsgpack.Unmarshal: can't unmarshal into non-pointer %T", v)
+	}
+	return d.unmarshalValue(rv.Elem())
+}
+
+// unmarshalValue decodes the next value into rv. If it returns ErrVoid, nothing was consumed and rv is unmodified.
+func (d *Decoder) unmarshalValue(rv reflect.Value) error {
+	if d.PeekType() == TypeNil {
+		rv.SetZero()
+		return d.Skip()
+	}
+	if rv.CanAddr() {
+		switch u := rv.Addr().Interface().(type) {
+		case interface{ DecodeMsgpack(*Decoder) error }:
+			return u.DecodeMsgpack(d)
+		case interface{ UnmarshalMsgpack([]byte) error }:
+			b, err := d.DecodeRaw()
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalMsgpack(b)
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			// Only allocate once we know the value isn't void.
+			p := reflect.New(rv.Type().Elem())
+			if err := d.unmarshalValue(p.Elem()); err != nil {
+				return err
+			}
+			rv.Set(p)
+			return nil
+		}
+		return d.unmarshalValue(rv.Elem())
+
+	case reflect.Interface:
+		v, err := d.DecodeValue()
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			rv.SetZero()
+			return nil
+		}
+		vv := reflect.ValueOf(v)
+		if !vv.Type().AssignableTo(rv.Type()) {
+			return fmt.Errorf("fastmsgpack.Unmarshal: can't assign %T to %s", v, rv.Type())
+		}
+		rv.Set(vv)
+		return nil
+
+	case reflect.Bool:
+		v, err := d.DecodeBool()
+		if err != nil {
+			return err
+		}
+		rv.SetBool(v)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := d.DecodeIntChecked()
+		if errors.Is(err, ErrIntOverflow) {
+			u, _ := d.DecodeUint()
+			return fmt.Errorf("fastmsgpack.Unmarshal: %d overflows %s", u, rv.Type())
+		}
+		if err != nil {
+			return err
+		}
+		if rv.OverflowInt(int64(v)) {
+			return fmt.Errorf("fastmsgpack.Unmarshal: %d overflows %s", v, rv.Type())
+		}
+		rv.SetInt(int64(v))
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v, err := d.DecodeUint()
+		if err != nil {
+			return err
+		}
+		if rv.OverflowUint(v) {
+			return fmt.Errorf("fastmsgpack.Unmarshal: %d overflows %s", v, rv.Type())
+		}
+		rv.SetUint(v)
+		return nil
+
+	case reflect.Float32:
+		v, err := d.DecodeFloat32()
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(float64(v))
+		return nil
+
+	case reflect.Float64:
+		v, err := d.DecodeFloat64()
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(v)
+		return nil
+
+	case reflect.String:
+		v, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+		rv.SetString(v)
+		return nil
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && d.PeekType() != TypeArray {
+			b, err := d.DecodeBytes()
+			if err != nil {
+				return err
+			}
+			rv.SetBytes(b)
+			return nil
+		}
+		return d.unmarshalSlice(rv)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && d.PeekType() != TypeArray {
+			b, err := d.DecodeBytes()
+			if err != nil {
+				return err
+			}
+			if len(b) != rv.Len() {
+				return fmt.Errorf("fastmsgpack.Unmarshal: can't unmarshal %d bytes into %s", len(b), rv.Type())
+			}
+			reflect.Copy(rv, reflect.ValueOf(b))
+			return nil
+		}
+		return d.unmarshalArray(rv)
+
+	case reflect.Map:
+		return d.unmarshalMap(rv)
+
+	case reflect.Struct:
+		switch rv.Type() {
+		case timeType:
+			v, err := d.DecodeTime()
+			if err != nil {
+				return err
+			}
+			rv.Set(reflect.ValueOf(v))
+			return nil
+		case extensionType:
+			v, err := d.DecodeValue()
+			if err != nil {
+				return err
+			}
+			e, ok := v.(Extension)
+			if !ok {
+				return fmt.Errorf("fastmsgpack.Unmarshal: can't assign %T to %s", v, rv.Type())
+			}
+			rv.Set(reflect.ValueOf(e))
+			return nil
+		}
+		return d.unmarshalStruct(rv)
+
+	default:
+		return fmt.Errorf("fastmsgpack.Unmarshal: can't unmarshal into %s", rv.Type())
+	}
+}
+
+func (d *Decoder) unmarshalSlice(rv reflect.Value) error {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	s := reflect.MakeSlice(rv.Type(), elements, elements)
+	var voided int
+	for i := 0; elements > i; i++ {
+		if err := d.unmarshalValue(s.Index(i - voided)); err != nil {
+			if err == ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				voided++
+				continue
+			}
+			return err
+		}
+	}
+	rv.Set(s.Slice(0, elements-voided))
+	return nil
+}
+
+func (d *Decoder) unmarshalArray(rv reflect.Value) error {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	var n int
+	for i := 0; elements > i; i++ {
+		if n >= rv.Len() {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.unmarshalValue(rv.Index(n)); err != nil {
+			if err == ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		n++
+	}
+	for ; rv.Len() > n; n++ {
+		rv.Index(n).SetZero()
+	}
+	return nil
+}
+
+func (d *Decoder) unmarshalMap(rv reflect.Value) error {
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), elements))
+	}
+	kt := rv.Type().Key()
+	vt := rv.Type().Elem()
+	for i := 0; elements > i; i++ {
+		k := reflect.New(kt).Elem()
+		if err := d.unmarshalValue(k); err != nil {
+			if err == ErrVoid {
+				if err := d.skipKeyValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		v := reflect.New(vt).Elem()
+		if err := d.unmarshalValue(v); err != nil {
+			if err == ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		rv.SetMapIndex(k, v)
+	}
+	return nil
+}
+
+func (d *Decoder) unmarshalStruct(rv reflect.Value) error {
+	si := getStructInfo(rv.Type())
+	if d.PeekType() == TypeArray {
+		return d.unmarshalStructArray(rv, si)
+	}
+	elements, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; elements > i; i++ {
+		k, err := d.DecodeString()
+		if err != nil {
+			if err == ErrVoid {
+				if err := d.skipKeyValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		n, ok := si.byName[k]
+		if !ok {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.unmarshalValue(fieldByIndexAlloc(rv, si.fields[n].index)); err != nil {
+			if err == ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalStructArray decodes a struct that was encoded with as_array. The values are assigned to the fields in order. Void values leave their field untouched.
+func (d *Decoder) unmarshalStructArray(rv reflect.Value, si *structInfo) error {
+	elements, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	for i := 0; elements > i; i++ {
+		if i >= len(si.fields) {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.unmarshalValue(fieldByIndexAlloc(rv, si.fields[i].index)); err != nil {
+			if err == ErrVoid {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) skipKeyValue() error {
+	if err := d.Skip(); err != nil {
+		return err
+	}
+	return d.Skip()
+}
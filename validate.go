@@ -0,0 +1,213 @@
+package fastmsgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hexon/fastmsgpack/internal"
+)
+
+// Validate checks that data is exactly one well-formed msgpack value, without decoding it.
+// Next to all headers and lengths it checks timestamps, flavor tables (extension 18), that length-prefixed entries (extension 17) wrap exactly one value and that interned strings are within the dict given with WithDict.
+// Pass WithValidateUTF8 to also check that all strings are valid UTF-8. Limits given with WithLimits are enforced too.
+// Any error returned is a *DecodeError.
+func Validate(data []byte, opts ...DecodeOption) error {
+	var v validator
+	if len(opts) > 0 {
+		// Only pay for the options escaping to the heap if there are any.
+		opt := new(internal.DecodeOptions)
+		for _, o := range opts {
+			o(opt)
+		}
+		v.opt = *opt
+	}
+	if err := v.opt.CheckBytes(data); err != nil {
+		return decodeErrorAt(err, 0, "")
+	}
+	n, err := v.validateAt(data, 0)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return &DecodeError{Offset: n, Err: fmt.Errorf("%d bytes of trailing data", len(data)-n)}
+	}
+	return nil
+}
+
+type validator struct {
+	opt internal.DecodeOptions
+}
+
+// validateAt validates the value at the start of data, which lies at the given offset relative to the value currently being validated.
+func (v *validator) validateAt(data []byte, offset int) (int, error) {
+	n, err := v.validateValue(data)
+	if err != nil {
+		return 0, decodeErrorAt(err, offset, "")
+	}
+	return n, nil
+}
+
+// descend checks the limits when entering a map, array or wrapping extension. opt.Ascend must be called when leaving it.
+func (v *validator) descend(elements, perElement, remaining int) error {
+	if err := v.opt.Descend(); err != nil {
+		return err
+	}
+	return v.opt.CheckElements(elements, perElement, remaining)
+}
+
+func (v *validator) validateString(s string) error {
+	if v.opt.ValidateUTF8 && !utf8.ValidString(s) {
+		return errors.New("string is not valid UTF-8")
+	}
+	return nil
+}
+
+func (v *validator) validateValue_array(data []byte, offset, elements int) (int, error) {
+	if err := v.descend(elements, 1, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer v.opt.Ascend()
+	for i := 0; elements > i; i++ {
+		n, err := v.validateValue(data[offset:])
+		if err != nil {
+			return 0, decodeErrorAt(err, offset, internal.IndexSegment(i))
+		}
+		offset += n
+	}
+	return offset, nil
+}
+
+func (v *validator) validateValue_map(data []byte, offset, elements int) (int, error) {
+	if err := v.descend(elements, 2, len(data)-offset); err != nil {
+		return 0, err
+	}
+	defer v.opt.Ascend()
+	var keyStart int
+	for i := 0; 2*elements > i; i++ {
+		n, err := v.validateValue(data[offset:])
+		if err != nil {
+			var segment string
+			if i%2 == 1 {
+				segment = keySegment(data[keyStart:], v.opt)
+			}
+			return 0, decodeErrorAt(err, offset, segment)
+		}
+		if i%2 == 0 {
+			keyStart = offset
+		}
+		offset += n
+	}
+	return offset, nil
+}
+
+// validateValue_ext validates the extension data, which starts header bytes after the start of the extension.
+func (v *validator) validateValue_ext(header int, data []byte, extType int8) error {
+	switch extType {
+	case 17, 18, 20:
+		if err := v.descend(0, 0, 0); err != nil {
+			return err
+		}
+		defer v.opt.Ascend()
+	}
+	switch extType {
+	case -1: // Timestamp
+		_, err := internal.DecodeTimestamp(data)
+		return err
+
+	case -128: // Interned string
+		n, ok := internal.DecodeBytesToUint(data)
+		if !ok {
+			return errors.New("failed to decode index number of interned string")
+		}
+		if v.opt.Dict != nil {
+			_, err := v.opt.Dict.LookupString(n)
+			return err
+		}
+		return nil
+
+	case 17: // Length-prefixed entry
+		n, err := v.validateAt(data, header)
+		if err != nil {
+			return err
+		}
+		if n != len(data) {
+			return fmt.Errorf("length-prefixed entry of %d bytes contains a value of %d bytes", len(data), n)
+		}
+		return nil
+
+	case 18: // Flavor pick
+		return v.validateFlavor(header, data)
+
+	case 20: // Injection
+		if v.opt.Injections != nil {
+			_, err := internal.DecodeInjectionExtension(data, v.opt)
+			return err
+		}
+		if _, ok := internal.DecodeBytesToUint(data); !ok {
+			return errors.New("failed to decode index number of inject extension")
+		}
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+// validateFlavor checks that all jump targets of the flavor table point after the table and that each of them is a valid value.
+func (v *validator) validateFlavor(header int, data []byte) error {
+	tableEnd, err := walkFlavorTable(data, nil)
+	if err != nil {
+		return err
+	}
+	_, err = walkFlavorTable(data, func(_, j uint64, _ bool) error {
+		if j < uint64(tableEnd) || j >= uint64(len(data)) {
+			return fmt.Errorf("flavor jump target %d is outside of the data (%d-%d)", j, tableEnd, len(data))
+		}
+		_, err := v.validateAt(data[j:], header+int(j))
+		return err
+	})
+	return err
+}
+
+// walkFlavorTable parses the table of a flavor extension, calls fn (if not nil) for every case and the else case and returns the length of the table.
+// match is meaningless for the else case.
+func walkFlavorTable(data []byte, fn func(match, jump uint64, isElse bool) error) (int, error) {
+	offset := 0
+	uvarint := func() (uint64, error) {
+		n, sz := binary.Uvarint(data[offset:])
+		if sz <= 0 {
+			return 0, internal.ErrCorruptedFlavorData
+		}
+		offset += sz
+		return n, nil
+	}
+	if _, err := uvarint(); err != nil {
+		return 0, err
+	}
+	numCases, err := uvarint()
+	if err != nil {
+		return 0, err
+	}
+	numJumps := numCases>>1 + numCases&1
+	for i := uint64(0); numJumps > i; i++ {
+		var match uint64
+		isElse := numCases>>1 <= i
+		if !isElse {
+			if match, err = uvarint(); err != nil {
+				return 0, err
+			}
+		}
+		j, err := uvarint()
+		if err != nil {
+			return 0, err
+		}
+		if fn != nil {
+			if err := fn(match, j, isElse); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return offset, nil
+}